@@ -1,38 +1,62 @@
 package client
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/fatih/color"
 	"github.com/rai-project/aws"
+	"github.com/rai-project/config"
 )
 
-// creates an AWS session. this uses STS
-// which allows us to provide a session
+// creates an AWS session. for deployments that
+// use STS this allows us to provide a session
 // that's only valid for certain amount
-// of time.
+// of time. the session is shared by all
+// the jobs in the process and its
+// credentials are refreshed before they
+// expire.
 func (c *Client) createAWSSession() error {
 
+	deployment, err := c.deployment()
+	if err != nil {
+		return err
+	}
+
+	// the sts session is named after the job that created it
+	name := c.ID.Hex()
+	lifetime := time.Duration(0)
+	if deployment.STS {
+		lifetime = Config.AWSCredentialsLifetime
+	}
+
 	// Create an AWS session
-	var session *session.Session
-	var err error
-	if c.options.serverArch == "s390x" {
-		session, err = aws.NewSession( //for Minio
-			aws.Region(aws.AWSRegionUSEast1),
+	cached, err := sharedAWSSession(deployment.Name, lifetime, func() (*session.Session, error) {
+		region := aws.AWSRegion(deployment.Region)
+		if region == "" {
+			region = aws.AWSRegionUSEast1
+		}
+		opts := []aws.Option{
+			aws.Region(region),
 			aws.AccessKey(aws.Config.AccessKey),
 			aws.SecretKey(aws.Config.SecretKey),
-		)
-	} else {
-		session, err = aws.NewSession(
-			aws.Region(aws.AWSRegionUSEast1),
-			aws.AccessKey(aws.Config.AccessKey),
-			aws.SecretKey(aws.Config.SecretKey),
-			aws.Sts(c.ID.Hex()),
-		)
-	}
-
+		}
+		if deployment.STS {
+			opts = append(opts, aws.Sts(name))
+		}
+		return aws.NewSession(opts...)
+	})
 	if err != nil {
 		return err
 	}
-	c.awsSession = session
+	c.connection.mu.Lock()
+	c.awsSession = cached.session
+	c.connection.mu.Unlock()
+
+	if expiresAt := cached.provider.ExpiresAt(); config.IsVerbose && !expiresAt.IsZero() {
+		fprintln(c.options.stdout, color.GreenString("✱ Temporary AWS credentials expire at "+expiresAt.Format(time.RFC1123)+
+			" and will be refreshed automatically."))
+	}
 	return nil
 }
 
@@ -47,5 +71,8 @@ func (c *Client) Authenticate() error {
 		return err
 	}
 
+	// The docker push credentials are now resolved
+	c.registerSecrets()
+
 	return nil
 }
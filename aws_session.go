@@ -0,0 +1,135 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	awsgo "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// refreshingCredentialsProvider creates temporary credentials using newSession
+// and marks them as expired window before their lifetime ends so that
+// the aws sdk transparently retrieves new ones before they lapse
+type refreshingCredentialsProvider struct {
+	sync.Mutex
+	newSession func() (*session.Session, error)
+	lifetime   time.Duration
+	window     time.Duration
+	expiresAt  time.Time
+	sessionCfg *awsgo.Config
+}
+
+// Retrieve ...
+func (p *refreshingCredentialsProvider) Retrieve() (credentials.Value, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	sess, err := p.newSession()
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	val, err := sess.Config.Credentials.Get()
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	if p.sessionCfg == nil {
+		p.sessionCfg = sess.Config.Copy()
+	}
+	refreshed := !p.expiresAt.IsZero()
+	p.expiresAt = p.expiry(sess.Config.Credentials)
+	if refreshed {
+		log.WithField("expires_at", p.expiresAt).Debug("refreshed the temporary AWS credentials")
+	}
+	return val, nil
+}
+
+// expiry returns the time the credentials expire: the expiry reported
+// by the underlying provider, such as the end of the STS session, if it
+// is earlier than the configured lifetime
+func (p *refreshingCredentialsProvider) expiry(creds *credentials.Credentials) time.Time {
+	var expiresAt time.Time
+	if p.lifetime > 0 {
+		expiresAt = time.Now().Add(p.lifetime)
+	}
+	actual, err := creds.ExpiresAt()
+	if err != nil || actual.IsZero() {
+		return expiresAt
+	}
+	if expiresAt.IsZero() || actual.Before(expiresAt) {
+		return actual
+	}
+	return expiresAt
+}
+
+// IsExpired ...
+func (p *refreshingCredentialsProvider) IsExpired() bool {
+	p.Lock()
+	defer p.Unlock()
+	if p.sessionCfg == nil {
+		return true
+	}
+	if p.expiresAt.IsZero() {
+		// the credentials do not expire
+		return false
+	}
+	return time.Now().Add(p.window).After(p.expiresAt)
+}
+
+// ExpiresAt returns the time the current credentials expire. The zero
+// time is returned for credentials that do not expire.
+func (p *refreshingCredentialsProvider) ExpiresAt() time.Time {
+	p.Lock()
+	defer p.Unlock()
+	return p.expiresAt
+}
+
+// cachedAWSSession is a session shared by all the jobs in the process
+type cachedAWSSession struct {
+	session  *session.Session
+	provider *refreshingCredentialsProvider
+}
+
+var awsSessions = struct {
+	sync.Mutex
+	sessions map[string]*cachedAWSSession
+}{
+	sessions: map[string]*cachedAWSSession{},
+}
+
+// sharedAWSSession returns the session cached under key or creates it. The
+// session's credentials are retrieved through newSession and are refreshed
+// when they are about to expire. A lifetime of 0 is used for credentials
+// that do not expire.
+func sharedAWSSession(key string, lifetime time.Duration, newSession func() (*session.Session, error)) (*cachedAWSSession, error) {
+	awsSessions.Lock()
+	defer awsSessions.Unlock()
+
+	if cached, ok := awsSessions.sessions[key]; ok {
+		return cached, nil
+	}
+
+	provider := &refreshingCredentialsProvider{
+		newSession: newSession,
+		lifetime:   lifetime,
+		window:     Config.AWSCredentialsRefreshWindow,
+	}
+	creds := credentials.NewCredentials(provider)
+	// retrieve the credentials once so that errors are reported
+	// early and the session configuration is known
+	if _, err := creds.Get(); err != nil {
+		return nil, err
+	}
+	sess, err := session.NewSession(provider.sessionCfg.Copy().WithCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &cachedAWSSession{
+		session:  sess,
+		provider: provider,
+	}
+	awsSessions.sessions[key] = cached
+	return cached, nil
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	awsgo "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSTSProvider returns new credentials that expire at expiresAt
+type fakeSTSProvider struct {
+	retrieved int
+	expiresAt time.Time
+}
+
+func (p *fakeSTSProvider) Retrieve() (credentials.Value, error) {
+	p.retrieved++
+	return credentials.Value{AccessKeyID: "AKIAFAKE", SecretAccessKey: "fake-secret"}, nil
+}
+
+func (p *fakeSTSProvider) IsExpired() bool {
+	return time.Now().After(p.expiresAt)
+}
+
+func (p *fakeSTSProvider) ExpiresAt() time.Time {
+	return p.expiresAt
+}
+
+func (p *fakeSTSProvider) newSession() (*session.Session, error) {
+	return session.NewSession(awsgo.NewConfig().
+		WithRegion("us-east-1").
+		WithCredentials(credentials.NewCredentials(p)))
+}
+
+func TestRefreshingCredentialsProvider(t *testing.T) {
+	fake := &fakeSTSProvider{expiresAt: time.Now().Add(20 * time.Minute)}
+	provider := &refreshingCredentialsProvider{
+		newSession: fake.newSession,
+		lifetime:   time.Hour,
+		window:     5 * time.Minute,
+	}
+	assert.True(t, provider.IsExpired(), "the credentials are retrieved on first use")
+
+	val, err := provider.Retrieve()
+	require.NoError(t, err)
+	assert.Equal(t, "AKIAFAKE", val.AccessKeyID)
+	assert.False(t, provider.IsExpired())
+	assert.Equal(t, fake.expiresAt, provider.ExpiresAt(), "the session ends before the configured lifetime")
+
+	// within the refresh window of the session
+	fake.expiresAt = time.Now().Add(time.Minute)
+	_, err = provider.Retrieve()
+	require.NoError(t, err)
+	assert.True(t, provider.IsExpired())
+
+	// the session outlives the configured lifetime
+	fake.expiresAt = time.Now().Add(24 * time.Hour)
+	_, err = provider.Retrieve()
+	require.NoError(t, err)
+	assert.False(t, provider.IsExpired())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), provider.ExpiresAt(), time.Minute)
+}
+
+func TestRefreshingCredentialsProviderWithoutExpiry(t *testing.T) {
+	provider := &refreshingCredentialsProvider{
+		newSession: func() (*session.Session, error) {
+			return session.NewSession(awsgo.NewConfig().
+				WithRegion("us-east-1").
+				WithCredentials(credentials.NewStaticCredentials("AKIAFAKE", "fake-secret", "")))
+		},
+	}
+	_, err := provider.Retrieve()
+	require.NoError(t, err)
+	assert.False(t, provider.IsExpired())
+	assert.True(t, provider.ExpiresAt().IsZero())
+}
+
+func TestSharedAWSSession(t *testing.T) {
+	fake := &fakeSTSProvider{expiresAt: time.Now().Add(time.Hour)}
+	key := "test-shared-aws-session"
+	defer func() {
+		awsSessions.Lock()
+		delete(awsSessions.sessions, key)
+		awsSessions.Unlock()
+	}()
+
+	first, err := sharedAWSSession(key, time.Hour, fake.newSession)
+	require.NoError(t, err)
+	second, err := sharedAWSSession(key, time.Hour, fake.newSession)
+	require.NoError(t, err)
+	assert.True(t, first == second, "the session is cached")
+	assert.Equal(t, 1, fake.retrieved)
+
+	// the sdk refreshes the credentials once they are about to expire
+	first.provider.Lock()
+	first.provider.expiresAt = time.Now()
+	first.provider.Unlock()
+	val, err := first.session.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "AKIAFAKE", val.AccessKeyID)
+	assert.Equal(t, 2, fake.retrieved)
+}
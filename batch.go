@@ -0,0 +1,293 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	colorable "github.com/mattn/go-colorable"
+	"github.com/rai-project/config"
+)
+
+// BatchSubmission is a project directory submitted as part of a batch
+type BatchSubmission struct {
+	// Label prefixes the log lines of the job. It defaults to the
+	// name of the directory.
+	Label         string
+	Directory     string
+	BuildFilePath string
+	// Options are applied after the options shared by the batch
+	Options []Option
+}
+
+// BatchResult is the outcome of a submission of the batch
+type BatchResult struct {
+	Label     string        `json:"label"`
+	Directory string        `json:"directory"`
+	Result    JobResult     `json:"result"`
+	Duration  time.Duration `json:"duration"`
+	Err       error         `json:"-"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Failed returns true if the job could not be run, failed, was
+// cancelled or interrupted, or if one of its commands failed
+func (r BatchResult) Failed() bool {
+	if r.Err != nil {
+		return true
+	}
+	if s := r.Result.Status; s == JobFailed || s == JobCanceled || s == JobInterrupted {
+		return true
+	}
+	for _, cmd := range r.Result.Commands {
+		if cmd.Status == CommandFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// prefixWriter prefixes every line written to it. Complete lines are
+// written atomically so that the output of concurrent jobs does not
+// interleave within a line. A carriage return, used to redraw progress
+// bars, ends a line as well so that the frames are not buffered.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    bytes.Buffer
+	// lastCR is set if the last line ended with a carriage return
+	lastCR bool
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		data := p.buf.Bytes()
+		i := bytes.IndexAny(data, "\r\n")
+		if i < 0 {
+			// keep the incomplete line for the next write
+			break
+		}
+		if data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			i++
+		}
+		line := string(p.buf.Next(i + 1))
+		switch {
+		case line == "\r":
+			// a frame starting with a carriage return
+		case line == "\n" && p.lastCR:
+			// the end of a \r\n split across writes
+		default:
+			p.writeLine(line)
+		}
+		p.lastCR = strings.HasSuffix(line, "\r")
+	}
+	return len(b), nil
+}
+
+func (p *prefixWriter) writeLine(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	io.WriteString(p.w, p.prefix+line)
+}
+
+// Close flushes the incomplete line, if any
+func (p *prefixWriter) Close() error {
+	if p.buf.Len() != 0 {
+		p.writeLine(p.buf.String() + "\n")
+		p.buf.Reset()
+	}
+	return nil
+}
+
+func batchLabels(submissions []BatchSubmission) []string {
+	labels := make([]string, len(submissions))
+	width := 0
+	for ii, s := range submissions {
+		label := s.Label
+		if label == "" {
+			label = filepath.Base(filepath.Clean(s.Directory))
+		}
+		labels[ii] = label
+		if len(label) > width {
+			width = len(label)
+		}
+	}
+	for ii, label := range labels {
+		labels[ii] = label + strings.Repeat(" ", width-len(label))
+	}
+	return labels
+}
+
+// runBatchJob runs a job to completion. pace is received from before the
+// job is published.
+func runBatchJob(ctx context.Context, c *Client, pace <-chan time.Time) error {
+	err := c.submit(ctx, func() error {
+		select {
+		case <-pace:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if err != nil {
+		c.Job.fail(err)
+		return err
+	}
+	return c.Wait()
+}
+
+// SubmitBatch submits the project directories and waits for their jobs
+// to complete. At most concurrency jobs are in flight at once (the
+// batch_concurrency config value is used if it is not positive) and the
+// jobs are published no faster than the configured rate limit. The log
+// lines of each job are prefixed with the label of the submission. The
+// results are returned in the order of the submissions.
+func SubmitBatch(ctx context.Context, submissions []BatchSubmission, concurrency int, opts ...Option) []BatchResult {
+	if concurrency <= 0 {
+		concurrency = Config.BatchConcurrency
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	shared := Options{}
+	for _, o := range opts {
+		o(&shared)
+	}
+	var stdout, stderr io.Writer = colorable.NewColorableStdout(), colorable.NewColorableStderr()
+	if !config.App.Color {
+		stdout, stderr = colorable.NewNonColorable(stdout), colorable.NewNonColorable(stderr)
+	}
+	if shared.stdout != nil {
+		stdout = shared.stdout
+	}
+	if shared.stderr != nil {
+		stderr = shared.stderr
+	}
+	jsonLines := shared.outputFormat == JSONLinesOutput
+
+	labels := batchLabels(submissions)
+	results := make([]BatchResult, len(submissions))
+	for ii, s := range submissions {
+		results[ii] = BatchResult{
+			Label:     strings.TrimSpace(labels[ii]),
+			Directory: s.Directory,
+		}
+	}
+
+	base, err := New(opts...)
+	if err != nil {
+		for ii := range results {
+			results[ii].Err = err
+			results[ii].Error = Secrets.Redact(err.Error())
+		}
+		return results
+	}
+	// the jobs share the connections of the client
+	defer base.Disconnect()
+
+	// the rate limit defaults to the ratelimit config value
+	var pace <-chan time.Time
+	if base.options.ratelimit > 0 {
+		ticker := time.NewTicker(base.options.ratelimit)
+		defer ticker.Stop()
+		pace = ticker.C
+	} else {
+		ch := make(chan time.Time)
+		close(ch)
+		pace = ch
+	}
+
+	mu := &sync.Mutex{}
+	clients := make([]*Client, len(submissions))
+	writers := []io.Closer{}
+
+	for ii, s := range submissions {
+		// the progress bar and the spinner redraw a single line, which
+		// cannot be shared by the jobs
+		jobOpts := []Option{Directory(s.Directory), NoProgress(true)}
+		if s.BuildFilePath != "" {
+			jobOpts = append(jobOpts, BuildFilePath(s.BuildFilePath))
+		}
+		// json lines records carry the job id and are not prefixed
+		prefix := ""
+		if !jsonLines {
+			prefix = "[" + labels[ii] + "] "
+		}
+		out := &prefixWriter{mu: mu, w: stdout, prefix: prefix}
+		errw := &prefixWriter{mu: mu, w: stderr, prefix: prefix}
+		writers = append(writers, out, errw)
+		jobOpts = append(jobOpts, Stdout(out), Stderr(errw))
+		jobOpts = append(jobOpts, s.Options...)
+		clients[ii] = base.newJobClient(jobOpts...)
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for ii, c := range clients {
+		wg.Add(1)
+		go func(ii int, c *Client) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				c.Job.abort()
+				results[ii].Err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			results[ii].Err = runBatchJob(ctx, c, pace)
+			results[ii].Duration = time.Since(start)
+			results[ii].Result = c.Result()
+		}(ii, c)
+	}
+	wg.Wait()
+
+	for _, w := range writers {
+		w.Close()
+	}
+	for ii := range results {
+		if err := results[ii].Err; err != nil {
+			results[ii].Error = Secrets.Redact(err.Error())
+		}
+	}
+	return results
+}
+
+// BatchSummary renders the batch results as a table
+func BatchSummary(results []BatchResult) string {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LABEL\tJOB\tDURATION\tSTATUS\t")
+	failed := 0
+	for _, r := range results {
+		status := "ok"
+		switch {
+		case r.Err != nil:
+			status = "error: " + r.Error
+		case r.Failed():
+			status = "failed"
+		}
+		if r.Failed() {
+			failed++
+		}
+		jobID := r.Result.JobID
+		if jobID == "" {
+			jobID = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", r.Label, jobID, r.Duration.Round(time.Second), status)
+	}
+	fmt.Fprintf(w, "TOTAL\t%d jobs\t\t%d failed\t\n", len(results), failed)
+	w.Flush()
+	return buf.String()
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchLabels(t *testing.T) {
+	labels := batchLabels([]BatchSubmission{
+		{Directory: "/home/ta/submissions/team_a/"},
+		{Directory: "/home/ta/submissions/b", Label: "team_bravo"},
+	})
+	assert.Equal(t, []string{"team_a    ", "team_bravo"}, labels)
+}
+
+func TestPrefixWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	mu := &sync.Mutex{}
+	a := &prefixWriter{mu: mu, w: buf, prefix: "[a] "}
+	b := &prefixWriter{mu: mu, w: buf, prefix: "[b] "}
+
+	a.Write([]byte("first "))
+	b.Write([]byte("second\nthird"))
+	a.Write([]byte("line\n"))
+	b.Close()
+
+	assert.Equal(t, "[b] second\n[a] first line\n[b] third\n", buf.String())
+}
+
+func TestPrefixWriterCarriageReturn(t *testing.T) {
+	buf := new(bytes.Buffer)
+	a := &prefixWriter{mu: &sync.Mutex{}, w: buf, prefix: "[a] "}
+
+	for ii := 0; ii < 1000; ii++ {
+		a.Write([]byte("\r| waiting"))
+	}
+	assert.Equal(t, len("| waiting"), a.buf.Len(), "the frames are not buffered")
+	a.Write([]byte("\rdone\r"))
+	a.Write([]byte("\nnext\r\n"))
+	a.Close()
+
+	assert.Equal(t, strings.Repeat("[a] | waiting\r", 1000)+"[a] done\r[a] next\r\n", buf.String())
+}
+
+func TestBatchSummary(t *testing.T) {
+	exitCode := 2
+	results := []BatchResult{
+		{Label: "team_a", Result: JobResult{JobID: "5b1a2c3d4e5f6a7b8c9d0e1f"}, Duration: 90 * time.Second},
+		{Label: "team_b", Result: JobResult{
+			JobID:    "5b1a2c3d4e5f6a7b8c9d0e20",
+			Commands: []CommandResult{{Index: 1, Command: "make", Status: CommandFailed, ExitCode: &exitCode}},
+		}},
+		{Label: "team_c", Err: errors.New("no build file"), Error: "no build file"},
+	}
+	assert.True(t, results[1].Failed())
+	assert.True(t, results[2].Failed())
+	assert.False(t, results[0].Failed())
+	assert.True(t, BatchResult{Result: JobResult{Status: JobFailed}}.Failed(), "the job failed without command tracking")
+
+	summary := BatchSummary(results)
+	lines := strings.Split(strings.TrimSpace(summary), "\n")
+	assert.Len(t, lines, 5)
+	assert.Contains(t, lines[1], "1m30s")
+	assert.Contains(t, lines[2], "failed")
+	assert.Contains(t, lines[3], "error: no build file")
+	assert.Contains(t, lines[4], "2 failed")
+}
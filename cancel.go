@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/acarl005/stripansi"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/rai-project/config"
+	"github.com/rai-project/model"
+	"github.com/rai-project/pubsub/redis"
+	"github.com/rai-project/serializer/json"
+)
+
+// CancelOutcome is what the server did with a cancelled job
+type CancelOutcome string
+
+const (
+	// CancelDequeued means the job was removed from the queue before it started
+	CancelDequeued CancelOutcome = "dequeued"
+	// CancelKilled means the running job was killed
+	CancelKilled CancelOutcome = "killed"
+	// CancelAlreadyFinished means the job had completed before it could be cancelled
+	CancelAlreadyFinished CancelOutcome = "finished"
+)
+
+// CancelRequest is published on the control channel to cancel a job.
+// Unlike the job request, the secret key is not sent since every
+// subscriber of the control channel receives the request. The request
+// is instead signed with the secret key of the profile. The server
+// looks up the secret key of AccessKey, as it does for the user_access_key
+// header of the job request, checks the signature with Verify, checks
+// that the job was published with the same access key and rejects the
+// requests whose RequestedAt is too old to be replayed.
+type CancelRequest struct {
+	ID          string    `json:"id"`
+	UploadKey   string    `json:"upload_key"`
+	Username    string    `json:"username"`
+	Queue       string    `json:"queue"`
+	RequestedAt time.Time `json:"requested_at"`
+	AccessKey   string    `json:"access_key"`
+	// Signature is the hex encoded HMAC-SHA256 of the other fields
+	Signature string `json:"signature"`
+}
+
+// signedContent is the content of the request covered by the signature
+func (r CancelRequest) signedContent() []byte {
+	return []byte(strings.Join([]string{
+		r.ID,
+		r.UploadKey,
+		r.Username,
+		r.Queue,
+		r.RequestedAt.UTC().Format(time.RFC3339Nano),
+		r.AccessKey,
+	}, "\n"))
+}
+
+func (r CancelRequest) signature(secretKey string) []byte {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write(r.signedContent())
+	return mac.Sum(nil)
+}
+
+// Sign sets the access key and signs the request with the secret key
+func (r *CancelRequest) Sign(accessKey, secretKey string) {
+	r.AccessKey = accessKey
+	r.Signature = hex.EncodeToString(r.signature(secretKey))
+}
+
+// Verify returns true if the request was signed with secretKey
+func (r CancelRequest) Verify(secretKey string) bool {
+	sig, err := hex.DecodeString(r.Signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(sig, r.signature(secretKey))
+}
+
+// cancelMarker prefixes the acknowledgement of a cancellation sent by
+// the server on the log channel of the job, for example
+//
+//	::rai-cancel:: killed
+func cancelMarker() string {
+	return "::" + config.App.Name + "-cancel::"
+}
+
+// controlChannel is the channel the cancellation requests are published to
+func controlChannel() string {
+	if Config.ControlChannel != "" {
+		return Config.ControlChannel
+	}
+	return config.App.Name + "/control"
+}
+
+// parseCancelAck returns the outcome if the response acknowledges a cancellation
+func parseCancelAck(resp model.JobResponse) (CancelOutcome, bool) {
+	body := strings.TrimSpace(stripansi.Strip(string(resp.Body)))
+	if !strings.HasPrefix(body, cancelMarker()) {
+		return "", false
+	}
+	outcome := CancelOutcome(strings.ToLower(strings.TrimSpace(strings.TrimPrefix(body, cancelMarker()))))
+	switch outcome {
+	case CancelDequeued, CancelKilled, CancelAlreadyFinished:
+		return outcome, true
+	default:
+		log.WithField("outcome", outcome).Debug("unknown cancellation acknowledgement")
+		return CancelKilled, true
+	}
+}
+
+// acknowledgeCancel delivers the acknowledgement to the pending Cancel call, if any
+func (j *Job) acknowledgeCancel(outcome CancelOutcome) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.cancelAck == nil {
+		return
+	}
+	select {
+	case j.cancelAck <- outcome:
+	default:
+	}
+}
+
+// Cancel asks the server to stop the job and waits for the server to
+// acknowledge it on the log channel of the job. The outcome tells
+// whether the job was removed from the queue, killed while running or
+// had already finished.
+func (j *Job) Cancel(ctx context.Context) (CancelOutcome, error) {
+	outcome, err := j.client.cancel(ctx)
+	// errors may contain credentials
+	return outcome, redactError(err)
+}
+
+func (c *Client) cancel(ctx context.Context) (CancelOutcome, error) {
+	switch c.Status() {
+	case JobSucceeded, JobFailed, JobCanceled:
+		return CancelAlreadyFinished, nil
+	case JobPending, JobUploading:
+		return "", errors.New("the job has not been submitted")
+	}
+
+	ack := make(chan CancelOutcome, 1)
+	c.Job.mu.Lock()
+	c.cancelAck = ack
+	c.Job.mu.Unlock()
+	defer func() {
+		c.Job.mu.Lock()
+		c.cancelAck = nil
+		c.Job.mu.Unlock()
+	}()
+
+	deployment, err := c.deployment()
+	if err != nil {
+		return "", err
+	}
+	conn, err := c.sharedPubSubConnection(deployment)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot create a redis connection")
+	}
+	publisher, err := redis.NewPublisher(conn)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot create redis publisher")
+	}
+	defer publisher.Stop()
+
+	if c.profile == nil {
+		return "", errors.New("the cancellation request cannot be signed without a profile")
+	}
+	profile := c.profile.Info()
+	req := CancelRequest{
+		ID:          c.ID.Hex(),
+		UploadKey:   c.uploadKey,
+		Username:    profile.Username,
+		Queue:       c.JobQueueName(),
+		RequestedAt: time.Now(),
+	}
+	req.Sign(profile.AccessKey, profile.SecretKey)
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	if err := publisher.Publish(controlChannel(), string(buf)); err != nil {
+		return "", errors.Wrap(err, "unable to publish the cancellation request")
+	}
+
+	fprintln(c.options.stdout, color.YellowString("✱ Cancelling job "+c.ID.Hex()+"."))
+
+	timeout := time.NewTimer(Config.CancelTimeout)
+	defer timeout.Stop()
+	var outcome CancelOutcome
+	select {
+	case outcome = <-ack:
+	case <-c.finished:
+		// the job completed without acknowledging the cancellation
+		outcome = CancelAlreadyFinished
+	case <-timeout.C:
+		return "", errors.Errorf("the server did not acknowledge the cancellation of job %v after %v", c.ID.Hex(), Config.CancelTimeout)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	switch outcome {
+	case CancelDequeued:
+		c.setStatus(JobCanceled)
+		fprintln(c.options.stdout, color.GreenString("✱ Job "+c.ID.Hex()+" was removed from the queue."))
+	case CancelKilled:
+		c.setStatus(JobCanceled)
+		fprintln(c.options.stdout, color.GreenString("✱ Job "+c.ID.Hex()+" was killed."))
+	case CancelAlreadyFinished:
+		fprintln(c.options.stdout, color.YellowString("✱ Job "+c.ID.Hex()+" had already finished."))
+	}
+	return outcome, nil
+}
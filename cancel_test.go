@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rai-project/model"
+	"github.com/rai-project/serializer/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCancelAck(t *testing.T) {
+	outcome, ok := parseCancelAck(model.JobResponse{Body: []byte(cancelMarker() + " Dequeued\n")})
+	assert.True(t, ok)
+	assert.Equal(t, CancelDequeued, outcome)
+
+	outcome, ok = parseCancelAck(model.JobResponse{Body: []byte("\x1b[31m" + cancelMarker() + " killed\x1b[0m")})
+	assert.True(t, ok)
+	assert.Equal(t, CancelKilled, outcome)
+
+	_, ok = parseCancelAck(model.JobResponse{Body: []byte("Building project")})
+	assert.False(t, ok)
+}
+
+func TestCancelFinishedJob(t *testing.T) {
+	clnt := &Client{connection: newConnection()}
+	newJob(clnt)
+
+	_, err := clnt.Job.Cancel(context.Background())
+	assert.Error(t, err, "a job that was not submitted cannot be cancelled")
+
+	clnt.setStatus(JobSucceeded)
+	outcome, err := clnt.Job.Cancel(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, CancelAlreadyFinished, outcome)
+}
+
+func TestAcknowledgeCancel(t *testing.T) {
+	clnt := &Client{connection: newConnection()}
+	newJob(clnt)
+
+	// acknowledgements without a pending cancellation are dropped
+	clnt.acknowledgeCancel(CancelKilled)
+
+	clnt.cancelAck = make(chan CancelOutcome, 1)
+	clnt.acknowledgeCancel(CancelDequeued)
+	assert.Equal(t, CancelDequeued, <-clnt.cancelAck)
+}
+
+func TestCancelRequestSignature(t *testing.T) {
+	req := CancelRequest{
+		ID:          "5b1a2c3d4e5f6a7b8c9d0e1f",
+		UploadKey:   "userdata/5b1a2c3d4e5f6a7b8c9d0e1f.tar.bz2",
+		Username:    "student",
+		Queue:       "rai_amd64",
+		RequestedAt: time.Now(),
+	}
+	req.Sign("access-key", "secret-key")
+	assert.Equal(t, "access-key", req.AccessKey)
+
+	buf, err := json.Marshal(req)
+	require.NoError(t, err)
+	assert.NotContains(t, string(buf), "secret-key")
+
+	var received CancelRequest
+	require.NoError(t, json.Unmarshal(buf, &received))
+	assert.True(t, received.Verify("secret-key"))
+	assert.False(t, received.Verify("another-secret-key"))
+
+	received.ID = "5b1a2c3d4e5f6a7b8c9d0e20"
+	assert.False(t, received.Verify("secret-key"), "the signature covers the job id")
+}
@@ -2,14 +2,16 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/Unknwon/com"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	"github.com/golang/snappy"
@@ -17,12 +19,8 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rai-project/archive"
 	"github.com/rai-project/auth"
-	"github.com/rai-project/auth/provider"
 	"github.com/rai-project/broker"
-	"github.com/rai-project/broker/rabbitmq"
-	"github.com/rai-project/broker/sqs"
 	"github.com/rai-project/config"
-	"github.com/rai-project/database"
 	"github.com/rai-project/model"
 	"github.com/rai-project/pubsub"
 	"github.com/rai-project/pubsub/redis"
@@ -31,28 +29,22 @@ import (
 	"github.com/rai-project/serializer/json"
 	"github.com/rai-project/store"
 	"github.com/rai-project/store/s3"
-	"gopkg.in/mgo.v2/bson"
 )
 
+// Client submits jobs to the server. The connections (authentication,
+// AWS session, broker and pubsub connection) are shared by all the jobs
+// submitted by the client. The embedded Job is the job of the client
+// which is driven by Validate, Upload, Publish, Subscribe and Wait;
+// Submit creates additional jobs.
 type Client struct {
-	ID                    bson.ObjectId
-	uploadKey             string
-	awsSession            *session.Session
-	mongodb               database.Database
-	options               Options
-	broker                broker.Broker
-	pubsubConn            pubsub.Connection
-	profile               auth.Profile
-	serializer            serializer.Serializer
-	subscribers           []pubsub.Subscriber
-	buildSpec             model.BuildSpecification
-	spinner               *spinner.Spinner
-	configJobQueueName    string
-	optionsJobQueueName   string
-	buildFileJobQueueName string
-	job                   *model.JobResponse
-	jobBody               interface{}
-	done                  chan bool
+	*connection
+	*Job
+	options             Options
+	serializer          serializer.Serializer
+	stdout              io.WriteCloser
+	stderr              io.WriteCloser
+	configJobQueueName  string
+	optionsJobQueueName string
 }
 
 // DefaultUploadExpiration ...
@@ -100,6 +92,10 @@ func New(opts ...Option) (*Client, error) {
 		o(&options)
 	}
 
+	// mask the secrets in everything written by the client
+	options.stdout = newRedactWriter(options.stdout)
+	options.stderr = newRedactWriter(options.stderr)
+
 	if options.directory == "" {
 		cwd, err := os.Getwd()
 		if err != nil {
@@ -113,13 +109,15 @@ func New(opts ...Option) (*Client, error) {
 	}
 
 	clnt := &Client{
-		ID:                  bson.NewObjectId(),
+		connection:          newConnection(),
 		options:             options,
 		serializer:          json.New(),
+		stdout:              options.stdout,
+		stderr:              options.stderr,
 		configJobQueueName:  Config.JobQueueName,
 		optionsJobQueueName: options.jobQueueName,
-		done:                make(chan bool),
 	}
+	newJob(clnt)
 
 	return clnt, nil
 }
@@ -156,14 +154,36 @@ func (c *Client) resultHandler(msgs <-chan pubsub.Message) error {
 				log.WithError(err).Debug("failed to unmarshal response data")
 				continue
 			}
-			if data.Kind == model.StderrResponse {
-				parse(data)
-				formatPrint(c.options.stderr, data)
-			} else if data.Kind == model.StdoutResponse {
-				parse(data)
+			if data.Kind != model.StderrResponse && data.Kind != model.StdoutResponse {
+				continue
+			}
+			if c.transcript != nil {
+				c.transcript.write(data)
+			}
+			if outcome, ok := parseCancelAck(data); ok {
+				c.acknowledgeCancel(outcome)
+				continue
+			}
+			if c.commandTracker != nil {
+				data.Body = []byte(c.commandTracker.process(data))
+			}
+			if c.Status() == JobQueued {
+				c.setStatus(JobRunning)
+			}
+			c.logs.append(data)
+			parse(data)
+			if c.isJSONLines() {
+				c.emitLog(data)
+			} else {
 				formatPrint(c.options.stderr, data)
 			}
 		}
+		if c.commandTracker != nil {
+			c.commandTracker.finish(time.Now())
+		}
+		c.closeTranscript()
+		c.logs.close()
+		c.closeFinished()
 		c.done <- true
 	}()
 	return nil
@@ -171,19 +191,29 @@ func (c *Client) resultHandler(msgs <-chan pubsub.Message) error {
 
 // Upload ...
 func (c *Client) Upload() error {
+	ctx := c.options.ctx
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if c.awsSession == nil {
 		log.Fatal("Expecting the awsSession to be set. Call Init before calling Upload")
 		return errors.New("invalid usage")
 	}
 
+	deployment, err := c.deployment()
+	if err != nil {
+		return err
+	}
 	st, err := s3.New(
-		s3.Session(c.awsSession),
+		s3.Session(c.storageSession(deployment)),
 		store.Bucket(Config.UploadBucketName),
 	)
 	if err != nil {
 		return err
 	}
 
+	c.setStatus(JobUploading)
+
 	fprintln(c.options.stdout, color.YellowString("✱ Preparing your project directory for upload."))
 
 	dir := c.options.directory
@@ -210,11 +240,25 @@ func (c *Client) Upload() error {
 		fprintln(c.options.stdout, color.YellowString("✱ Failed to set profile information "+err.Error()+"."))
 	}
 
+	// in json lines mode the progress bar is replaced
+	// by progress records
+	// the hash of the archive identifies the version of the project in the history
+	hash := sha256.New()
+	var uploadReader io.Reader = ctxReader{ctx: ctx, Reader: io.TeeReader(zippedReader, hash)}
+	var progressOutput io.Writer = c.options.stdout
+	if c.options.noProgress {
+		progressOutput = ioutil.Discard
+	}
+	if c.isJSONLines() {
+		uploadReader = &progressReader{Reader: uploadReader, enc: c.jsonLines}
+		progressOutput = ioutil.Discard
+	}
+
 	key, err := st.UploadFrom(
-		zippedReader,
+		uploadReader,
 		uploadKey,
 		s3.Expiration(uploadExpiration),
-		store.UploadProgressOutput(c.options.stdout),
+		store.UploadProgressOutput(progressOutput),
 		store.UploadMetadata(map[string]interface{}{
 			"id":                  c.ID,
 			"type":                "user_upload",
@@ -228,10 +272,17 @@ func (c *Client) Upload() error {
 		store.UploadProgressFinishMessage(color.GreenString("✱ Folder uploaded. Server is now processing your submission.")),
 	)
 	if err != nil {
+		// remove what was uploaded before the upload was aborted
+		if ctx.Err() != nil {
+			if err := st.Delete(uploadKey); err != nil {
+				log.WithError(err).WithField("key", uploadKey).Debug("failed to delete the partial upload")
+			}
+		}
 		return err
 	}
 
 	c.uploadKey = key
+	c.archiveHash = "sha256:" + hex.EncodeToString(hash.Sum(nil))
 
 	return nil
 }
@@ -264,6 +315,9 @@ func compressProfileInfo(prof auth.ProfileBase) (string, error) {
 
 // Publish ...
 func (c *Client) Publish() error {
+	if err := c.options.ctx.Err(); err != nil {
+		return err
+	}
 
 	profile := c.profile.Info()
 
@@ -276,7 +330,7 @@ func (c *Client) Publish() error {
 		ClientVersion:      config.App.Version,
 		UploadKey:          c.uploadKey,
 		User:               profile.User,
-		BuildSpecification: c.buildSpec,
+		BuildSpecification: c.jobBuildSpec(),
 	}
 
 	body, err := c.serializer.Marshal(jobRequest)
@@ -285,27 +339,17 @@ func (c *Client) Publish() error {
 	}
 
 	// create a broker object.
-	// this currently uses sqs, but should
-	// be abstracted out
-	var brkr broker.Broker
-	if c.options.serverArch == "s390x" {
-		brkr = rabbitmq.New(
-			rabbitmq.QueueName(c.JobQueueName()),
-			broker.Serializer(json.New()),
-		)
-	} else {
-		brkr, err = sqs.New(
-			sqs.QueueName(c.JobQueueName()),
-			broker.Serializer(c.serializer),
-			sqs.Session(c.awsSession),
-		)
+	// the kind of broker (sqs, rabbitmq, ...)
+	// is determined by the deployment
+	deployment, err := c.deployment()
+	if err != nil {
+		return err
 	}
+	brkr, err := c.sharedBroker(deployment)
 	if err != nil {
 		return err
 	}
-	c.broker = brkr
 
-	brkr.Connect()
 	log.Debug(color.GreenString("✱Submitting to queue= " + c.JobQueueName()))
 	err = brkr.Publish(
 		c.JobQueueName(),
@@ -325,9 +369,12 @@ func (c *Client) Publish() error {
 		return err
 	}
 
+	c.publishedAt = time.Now()
+	c.setStatus(JobQueued)
+
 	fprintln(c.options.stdout, color.GreenString("✱ Your job request has been posted to the queue."))
 
-	if c.options.stdout != nil {
+	if c.options.stdout != nil && !c.isJSONLines() && !c.options.noProgress {
 		c.spinner = spinner.New(spinner.CharSets[11], 100*time.Millisecond)
 		c.spinner.Suffix = " Waiting for the server to process your request..."
 		c.spinner.Writer = c.options.stdout
@@ -339,13 +386,15 @@ func (c *Client) Publish() error {
 
 // Subscribe ...
 func (c *Client) Subscribe() error {
-	redisConn, err := redis.New()
+	deployment, err := c.deployment()
+	if err != nil {
+		return err
+	}
+	redisConn, err := c.sharedPubSubConnection(deployment)
 	if err != nil {
 		return errors.Wrap(err, "cannot create a redis connection")
 	}
 
-	c.pubsubConn = redisConn
-
 	// the channel name is of the form rai/log-xxxxxxxx
 	subscribeChannel := config.App.Name + "/log-" + c.ID.Hex()
 	subscriber, err := redis.NewSubscriber(redisConn, subscribeChannel)
@@ -353,52 +402,113 @@ func (c *Client) Subscribe() error {
 		return errors.Wrap(err, "cannot create redis subscriber")
 	}
 
+	// record the job output if requested
+	c.startTranscript()
+
 	// run resultHandler for each message we get from
 	// the pubsub
 	c.resultHandler(subscriber.Start())
 
+	c.Job.mu.Lock()
 	c.subscribers = append(c.subscribers, subscriber)
+	c.Job.mu.Unlock()
 	return nil
 }
 
 // Connect to the brokers
 func (c *Client) Connect() error {
-	if err := c.broker.Connect(); err != nil {
-		return err
+	c.connection.mu.Lock()
+	defer c.connection.mu.Unlock()
+	for _, brkr := range c.brokers {
+		if err := brkr.Connect(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// Disconnect ...
+// Disconnect closes the connections shared by the jobs of the client
 func (c *Client) Disconnect() error {
+	// the notifications are bounded by the notify timeout
+	c.notifications.Wait()
+
+	c.connection.mu.Lock()
+	jobs := []*Job{c.Job}
+	for _, job := range c.jobs {
+		if job != c.Job {
+			jobs = append(jobs, job)
+		}
+	}
+	c.connection.mu.Unlock()
+
 	// stop subscribing to each of the subscribers
 	// we have listened to
-	for _, sub := range c.subscribers {
-		sub.Stop()
+	for _, job := range jobs {
+		job.stopSubscribers()
 	}
-	// close the pubsub connection if it exists
-	if c.pubsubConn != nil {
-		c.pubsubConn.Close()
+
+	c.connection.mu.Lock()
+	defer c.connection.mu.Unlock()
+	// close the pubsub connections of all the deployments
+	for name, conn := range c.pubsubConns {
+		conn.Close()
+		delete(c.pubsubConns, name)
 	}
-	if c.broker != nil {
-		return c.broker.Disconnect()
+	var err error
+	for key, brkr := range c.brokers {
+		if e := brkr.Disconnect(); e != nil && err == nil {
+			err = e
+		}
+		delete(c.brokers, key)
 	}
-	return nil
+	return err
 }
 
 // Wait until we are complete (got the end signal)
 func (c *Client) Wait() error {
+	return c.Job.Wait()
+}
+
+func (c *Client) wait() error {
+	// set by HandleSignals, which may be called concurrently
+	c.connection.mu.Lock()
+	interrupted := c.interrupted
+	c.connection.mu.Unlock()
+
 	// the channel is written to (or closed)
 	// when the end signal is received
-	<-c.done
+	select {
+	case <-c.done:
+	case <-interrupted:
+		notification := c.jobNotification(ErrInterrupted)
+		c.setStatus(notification.Status)
+		c.recordHistory()
+		c.notify(notification)
+		return ErrInterrupted
+	}
+	notification := c.jobNotification(nil)
+	c.setStatus(notification.Status)
+	c.printCommandSummary()
+	if err := c.writeJUnitReport(); err != nil {
+		log.WithError(err).Error("failed to write the junit report")
+	}
+	c.recordHistory()
+	if c.isJSONLines() {
+		c.emitResult()
+	}
+	c.notify(notification)
 	return nil
 }
 
-func (c *Client) authenticate(profilePath string) error {
+func (c *Client) authenticate() error {
+	// the profile is shared by the jobs of the client
+	if c.profile != nil {
+		return nil
+	}
 
 	fprintln(c.options.stdout, color.GreenString("✱ Checking your authentication credentials."))
 
-	prof, err := provider.New(auth.ProfilePath(profilePath))
+	prof, err := c.newProfile()
 	if err != nil {
 		return err
 	}
@@ -408,7 +518,7 @@ func (c *Client) authenticate(profilePath string) error {
 		return err
 	}
 	if !ok {
-		return errors.Errorf("cannot authenticate using the credentials in %v", profilePath)
+		return errors.Errorf("cannot authenticate using the credentials in %v", c.profileDescription())
 	}
 	c.profile = prof
 	return nil
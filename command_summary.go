@@ -0,0 +1,66 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// maxSummaryCommandLength truncates long commands in the summary table
+var maxSummaryCommandLength = 60
+
+func truncateCommand(cmd string) string {
+	cmd = strings.Join(strings.Fields(cmd), " ")
+	if len(cmd) <= maxSummaryCommandLength {
+		return cmd
+	}
+	return cmd[:maxSummaryCommandLength-3] + "..."
+}
+
+// CommandSummary renders the command results as a table with the
+// command, its duration and its exit code
+func CommandSummary(results []CommandResult) string {
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tCOMMAND\tDURATION\tEXIT CODE\t")
+	var total time.Duration
+	for _, r := range results {
+		duration := "-"
+		if r.Status != CommandNotRun {
+			duration = r.Duration.Round(time.Millisecond).String()
+		}
+		exitCode := "-"
+		switch {
+		case r.Status == CommandNotRun:
+			exitCode = "not run"
+		case r.ExitCode != nil && *r.ExitCode >= 0:
+			exitCode = fmt.Sprint(*r.ExitCode)
+		case r.Status == CommandFailed:
+			exitCode = "failed"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t\n", r.Index, truncateCommand(r.Command), duration, exitCode)
+		total += r.Duration
+	}
+	fmt.Fprintf(w, "\tTOTAL\t%s\t\t\n", total.Round(time.Millisecond))
+	w.Flush()
+	return buf.String()
+}
+
+// printCommandSummary prints the command summary table once the job completes
+func (c *Client) printCommandSummary() {
+	if !c.options.printCommandSummary || c.commandTracker == nil {
+		return
+	}
+	results := c.commandTracker.Results()
+	if len(results) == 0 {
+		return
+	}
+	fprintln(c.options.stdout, color.CyanString("✱ Command summary:"))
+	for _, line := range strings.Split(strings.TrimRight(CommandSummary(results), "\n"), "\n") {
+		fprintln(c.options.stdout, "  "+line)
+	}
+}
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acarl005/stripansi"
+	"github.com/rai-project/config"
+	"github.com/rai-project/model"
+)
+
+// CommandStatus ...
+type CommandStatus string
+
+const (
+	// CommandPassed means the next command (or the end marker) was reached
+	CommandPassed CommandStatus = "passed"
+	// CommandFailed means the job stopped while running the command
+	CommandFailed CommandStatus = "failed"
+	// CommandNotRun means the job stopped before the command was started
+	CommandNotRun CommandStatus = "not_run"
+)
+
+// maxCommandOutputLines bounds the output kept in memory for each command
+var maxCommandOutputLines = 1000
+
+// commandExitVariable holds the exit code of a build command until the
+// exit marker is echoed
+const commandExitVariable = "__command_exit"
+
+// CommandResult is the outcome of a single commands.build entry
+type CommandResult struct {
+	Index      int           `json:"index"`
+	Command    string        `json:"command"`
+	Status     CommandStatus `json:"status"`
+	ExitCode   *int          `json:"exit_code,omitempty"`
+	StartedAt  time.Time     `json:"started_at,omitempty"`
+	FinishedAt time.Time     `json:"finished_at,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Output     []string      `json:"-"`
+}
+
+// commandMarker is echoed by the server before each command so that
+// the client can find the command boundaries in the streamed output
+func commandMarker() string {
+	return "::" + config.App.Name + "-command::"
+}
+
+// commandTracker splits the streamed job output by command. The build
+// commands are interleaved with marker commands that echo
+//
+//	::rai-command:: start <index>
+//	::rai-command:: exit <index> <exit code>
+//	::rai-command:: end
+//
+// the exit marker is echoed by the command itself once it returns. A
+// command without an exit marker passed if the following marker is seen.
+// Commands after a failure are never started since the server stops the job.
+type commandTracker struct {
+	sync.Mutex
+	results []CommandResult
+	current int
+	ended   bool
+}
+
+func newCommandTracker(commands []string) *commandTracker {
+	t := &commandTracker{
+		current: -1,
+	}
+	for ii, cmd := range commands {
+		t.results = append(t.results, CommandResult{
+			Index:   ii,
+			Command: cmd,
+			Status:  CommandNotRun,
+		})
+	}
+	return t
+}
+
+// markedCommands returns the commands with the marker commands interleaved.
+// The exit marker is on its own line so that it runs even if the command
+// ends with a comment.
+func (t *commandTracker) markedCommands() []string {
+	res := []string{}
+	for ii, r := range t.results {
+		idx := strconv.Itoa(ii)
+		res = append(res, "echo "+commandMarker()+" start "+idx)
+		res = append(res, r.Command+"\n"+
+			commandExitVariable+"=$?; "+
+			"echo "+commandMarker()+" exit "+idx+" $"+commandExitVariable+"; "+
+			"exit $"+commandExitVariable)
+	}
+	res = append(res, "echo "+commandMarker()+" end")
+	return res
+}
+
+// process records the response and returns its body without the
+// marker lines, which should be hidden from the user
+func (t *commandTracker) process(resp model.JobResponse) string {
+	t.Lock()
+	defer t.Unlock()
+
+	at := resp.CreatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	kept := []string{}
+	for _, raw := range strings.Split(string(resp.Body), "\n") {
+		line := strings.TrimSpace(stripansi.Strip(raw))
+		if strings.HasPrefix(line, commandMarker()) {
+			t.processMarker(strings.Fields(strings.TrimPrefix(line, commandMarker())), at)
+			continue
+		}
+		// the server echoes the marker command before running it
+		if strings.Contains(line, "echo "+commandMarker()) {
+			continue
+		}
+		kept = append(kept, raw)
+		if t.current < 0 || t.current >= len(t.results) || t.ended {
+			continue
+		}
+		r := &t.results[t.current]
+		r.Output = append(r.Output, line)
+		if len(r.Output) > maxCommandOutputLines {
+			r.Output = r.Output[len(r.Output)-maxCommandOutputLines:]
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func (t *commandTracker) processMarker(fields []string, at time.Time) {
+	if len(fields) == 0 {
+		return
+	}
+	if fields[0] == "exit" {
+		if len(fields) < 3 {
+			return
+		}
+		idx, err := strconv.Atoi(fields[1])
+		if err != nil || idx < 0 || idx >= len(t.results) {
+			return
+		}
+		code, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return
+		}
+		status := CommandPassed
+		if code != 0 {
+			status = CommandFailed
+		}
+		t.complete(&t.results[idx], status, code, at)
+		return
+	}
+	// the previous command completed successfully
+	if t.current >= 0 && t.current < len(t.results) {
+		t.complete(&t.results[t.current], CommandPassed, 0, at)
+	}
+	switch fields[0] {
+	case "start":
+		if len(fields) < 2 {
+			return
+		}
+		idx, err := strconv.Atoi(fields[1])
+		if err != nil || idx < 0 || idx >= len(t.results) {
+			return
+		}
+		t.current = idx
+		t.results[idx].StartedAt = at
+	case "end":
+		t.ended = true
+	}
+}
+
+func (t *commandTracker) complete(r *CommandResult, status CommandStatus, exitCode int, at time.Time) {
+	if r.Status != CommandNotRun {
+		return
+	}
+	r.Status = status
+	r.FinishedAt = at
+	if !r.StartedAt.IsZero() {
+		r.Duration = at.Sub(r.StartedAt)
+	}
+	r.ExitCode = &exitCode
+}
+
+// finish is called once the job output stream is closed. The command that
+// was running when the stream closed, if any, failed. Its exit code is -1
+// if it did not echo its exit marker.
+func (t *commandTracker) finish(at time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	if t.ended || t.current < 0 || t.current >= len(t.results) {
+		return
+	}
+	t.complete(&t.results[t.current], CommandFailed, -1, at)
+}
+
+// Results returns a copy of the command results
+func (t *commandTracker) Results() []CommandResult {
+	t.Lock()
+	defer t.Unlock()
+	res := make([]CommandResult, len(t.results))
+	copy(res, t.results)
+	return res
+}
+
+// CommandResults returns the per command results of the job. It is
+// only populated if command tracking is enabled.
+func (c *Client) CommandResults() []CommandResult {
+	if c.commandTracker == nil {
+		return nil
+	}
+	return c.commandTracker.Results()
+}
+
+// tracksCommands returns true if the output of the job is split by
+// command. The command markers are how a failed build is told apart from
+// a successful one, so the commands are also tracked whenever the status
+// of the job is recorded in the history or sent to the notifiers.
+func (c *Client) tracksCommands() bool {
+	return c.options.trackCommands || !c.options.disableHistory || len(Config.Notifiers) != 0
+}
+
+// jobBuildSpec returns the build specification sent to the server. When
+// command tracking is enabled, the marker commands are interleaved with
+// the build commands.
+func (c *Client) jobBuildSpec() model.BuildSpecification {
+	spec := c.buildSpec
+	if !c.tracksCommands() || len(spec.Commands.Build) == 0 {
+		return spec
+	}
+	commands := []string{}
+	for _, cmd := range spec.Commands.Build {
+		commands = append(commands, string(cmd))
+	}
+	c.commandTracker = newCommandTracker(commands)
+	spec.Commands.Build = c.commandTracker.markedCommands()
+	return spec
+}
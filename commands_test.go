@@ -0,0 +1,77 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rai-project/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func trackerResponse(at time.Time, s string) model.JobResponse {
+	return model.JobResponse{
+		Kind:      model.StdoutResponse,
+		Body:      []byte(s),
+		CreatedAt: at,
+	}
+}
+
+func TestCommandTracker(t *testing.T) {
+	tracker := newCommandTracker([]string{"make", "./a.out", "./b.out"})
+	marked := tracker.markedCommands()
+	require.Len(t, marked, 7)
+	assert.True(t, strings.HasPrefix(marked[1], "make\n"))
+	assert.Contains(t, marked[1], "echo "+commandMarker()+" exit 0 $")
+
+	start := time.Now()
+	assert.Equal(t, "", tracker.process(trackerResponse(start, commandMarker()+" start 0")))
+	assert.Equal(t, "compiling", tracker.process(trackerResponse(start.Add(time.Second), "compiling")))
+	tracker.process(trackerResponse(start.Add(3*time.Second), commandMarker()+" start 1"))
+	// the exit code is read from the marker, not from the command output
+	tracker.process(trackerResponse(start.Add(4*time.Second), "the test exited with exit status 1"))
+	tracker.process(trackerResponse(start.Add(4*time.Second), commandMarker()+" exit 1 139"))
+	tracker.finish(start.Add(5 * time.Second))
+
+	results := tracker.Results()
+	require.Len(t, results, 3)
+	assert.Equal(t, CommandPassed, results[0].Status)
+	assert.Equal(t, 3*time.Second, results[0].Duration)
+	assert.Equal(t, 0, *results[0].ExitCode)
+	assert.Equal(t, []string{"compiling"}, results[0].Output)
+	assert.Equal(t, CommandFailed, results[1].Status)
+	assert.Equal(t, 139, *results[1].ExitCode)
+	assert.Equal(t, time.Second, results[1].Duration)
+	assert.Equal(t, CommandNotRun, results[2].Status)
+
+	report, err := JUnitReport("rai_build", "5b0f1e3b", results)
+	require.NoError(t, err)
+	xml := string(report)
+	assert.True(t, strings.Contains(xml, `<testsuite name="rai_build" tests="3" failures="1" skipped="1"`))
+	assert.True(t, strings.Contains(xml, "command failed with exit code 139"))
+}
+
+func TestCommandSummary(t *testing.T) {
+	code := 2
+	summary := CommandSummary([]CommandResult{
+		{Index: 0, Command: "make", Status: CommandFailed, ExitCode: &code, Duration: 1500 * time.Millisecond},
+		{Index: 1, Command: "./a.out", Status: CommandNotRun},
+	})
+	lines := strings.Split(strings.TrimSpace(summary), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"0", "make", "1.5s", "2"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"1", "./a.out", "-", "not", "run"}, strings.Fields(lines[2]))
+}
+
+func TestCommandTrackerWithoutExitMarker(t *testing.T) {
+	tracker := newCommandTracker([]string{"make"})
+	start := time.Now()
+	tracker.process(trackerResponse(start, commandMarker()+" start 0"))
+	tracker.finish(start.Add(time.Second))
+
+	results := tracker.Results()
+	require.Len(t, results, 1)
+	assert.Equal(t, CommandFailed, results[0].Status)
+	assert.Equal(t, -1, *results[0].ExitCode)
+}
@@ -0,0 +1,298 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRegression is returned by JobComparison.Err when the second job
+// regressed compared to the first one
+var ErrRegression = errors.New("performance regression detected")
+
+// higherIsBetterMetrics are the metrics for which a decrease is a regression
+var higherIsBetterMetrics = []string{"*correctness*", "*accuracy*"}
+
+// RegressionThresholds are the relative changes flagged as regressions
+type RegressionThresholds struct {
+	// CommandDuration is the relative increase of a command duration
+	CommandDuration float64
+	// MinCommandDuration ignores the commands faster than this in both jobs
+	MinCommandDuration time.Duration
+	// Metric is the relative change of a metric in its bad direction
+	Metric float64
+	// Metrics overrides Metric for the metrics whose name matches the glob
+	Metrics map[string]float64
+}
+
+// DefaultRegressionThresholds returns the thresholds of the client config
+func DefaultRegressionThresholds() RegressionThresholds {
+	return RegressionThresholds{
+		CommandDuration:    Config.RegressionCommandDuration,
+		MinCommandDuration: Config.RegressionMinDuration,
+		Metric:             Config.RegressionMetric,
+		Metrics:            Config.RegressionMetrics,
+	}
+}
+
+// metric returns the threshold of the metric. When several patterns
+// match, the longest one, which is the most specific, wins.
+func (t RegressionThresholds) metric(name string) float64 {
+	threshold, best := t.Metric, ""
+	for pattern, v := range t.Metrics {
+		if ok, _ := filepath.Match(pattern, name); !ok {
+			continue
+		}
+		if best == "" || len(pattern) > len(best) || (len(pattern) == len(best) && pattern < best) {
+			threshold, best = v, pattern
+		}
+	}
+	return threshold
+}
+
+func higherIsBetter(name string) bool {
+	for _, pattern := range higherIsBetterMetrics {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandComparison compares a build command of the two jobs
+type CommandComparison struct {
+	Index          int           `json:"index"`
+	Command        string        `json:"command"`
+	BaseStatus     CommandStatus `json:"base_status,omitempty"`
+	HeadStatus     CommandStatus `json:"head_status,omitempty"`
+	BaseDuration   time.Duration `json:"base_duration"`
+	HeadDuration   time.Duration `json:"head_duration"`
+	Regression     bool          `json:"regression"`
+	RegressionNote string        `json:"regression_note,omitempty"`
+}
+
+// MetricComparison compares a metric of the two jobs
+type MetricComparison struct {
+	MetricChange
+	Threshold  float64 `json:"threshold"`
+	Regression bool    `json:"regression"`
+}
+
+// JobComparison is the difference between a base job and a head job
+type JobComparison struct {
+	Base        string              `json:"base"`
+	Head        string              `json:"head"`
+	BaseStatus  JobStatus           `json:"base_status,omitempty"`
+	HeadStatus  JobStatus           `json:"head_status,omitempty"`
+	Commands    []CommandComparison `json:"commands,omitempty"`
+	Metrics     []MetricComparison  `json:"metrics,omitempty"`
+	Regressions []string            `json:"regressions,omitempty"`
+}
+
+// Regressed returns true if a regression was detected
+func (c JobComparison) Regressed() bool {
+	return len(c.Regressions) != 0
+}
+
+// Err returns ErrRegression if a regression was detected
+func (c JobComparison) Err() error {
+	if c.Regressed() {
+		return errors.Wrap(ErrRegression, strings.Join(c.Regressions, "; "))
+	}
+	return nil
+}
+
+// ExitCode is the exit code of a command gating on the comparison
+func (c JobComparison) ExitCode() int {
+	if c.Regressed() {
+		return 1
+	}
+	return 0
+}
+
+func compareCommands(base, head []CommandResult, thresholds RegressionThresholds) []CommandComparison {
+	n := len(base)
+	if len(head) > n {
+		n = len(head)
+	}
+	comparisons := []CommandComparison{}
+	for ii := 0; ii < n; ii++ {
+		cmp := CommandComparison{Index: ii}
+		if ii < len(base) {
+			cmp.Index = base[ii].Index
+			cmp.Command = base[ii].Command
+			cmp.BaseStatus = base[ii].Status
+			cmp.BaseDuration = base[ii].Duration
+		}
+		if ii < len(head) {
+			cmp.Index = head[ii].Index
+			cmp.Command = head[ii].Command
+			cmp.HeadStatus = head[ii].Status
+			cmp.HeadDuration = head[ii].Duration
+		}
+		switch {
+		case cmp.BaseStatus == CommandPassed && cmp.HeadStatus != "" && cmp.HeadStatus != CommandPassed:
+			cmp.Regression = true
+			cmp.RegressionNote = "the command " + string(cmp.HeadStatus)
+		case cmp.BaseStatus == CommandPassed && cmp.HeadStatus == CommandPassed:
+			if cmp.BaseDuration < thresholds.MinCommandDuration && cmp.HeadDuration < thresholds.MinCommandDuration {
+				break
+			}
+			if cmp.BaseDuration <= 0 || thresholds.CommandDuration <= 0 {
+				break
+			}
+			rel := float64(cmp.HeadDuration-cmp.BaseDuration) / float64(cmp.BaseDuration)
+			if rel > thresholds.CommandDuration {
+				cmp.Regression = true
+				cmp.RegressionNote = fmt.Sprintf("%+.1f%% slower", rel*100)
+			}
+		}
+		comparisons = append(comparisons, cmp)
+	}
+	return comparisons
+}
+
+func compareMetrics(base, head Metrics, thresholds RegressionThresholds) []MetricComparison {
+	comparisons := []MetricComparison{}
+	for _, ch := range DiffMetrics(base, head) {
+		// the command durations are compared with the commands
+		if strings.HasPrefix(ch.Name, "command.") {
+			continue
+		}
+		cmp := MetricComparison{
+			MetricChange: ch,
+			Threshold:    thresholds.metric(ch.Name),
+		}
+		if rel := ch.Relative(); !math.IsNaN(rel) {
+			if higherIsBetter(ch.Name) {
+				rel = -rel
+			}
+			cmp.Regression = rel > cmp.Threshold
+		} else if ch.HasOld && ch.HasNew && higherIsBetter(ch.Name) && ch.New < ch.Old {
+			cmp.Regression = true
+		}
+		comparisons = append(comparisons, cmp)
+	}
+	return comparisons
+}
+
+// CompareJobs compares the head job to the base job. The head job
+// regresses if it fails while the base job succeeded, if a command that
+// passed in the base job fails or becomes slower than the threshold, or
+// if a metric changes in its bad direction by more than its threshold.
+// Metrics are lower is better, except for correctness and accuracy.
+func CompareJobs(base, head HistoryEntry, thresholds RegressionThresholds) JobComparison {
+	cmp := JobComparison{
+		Base:       base.JobID,
+		Head:       head.JobID,
+		BaseStatus: base.Status,
+		HeadStatus: head.Status,
+		Commands:   compareCommands(base.Commands, head.Commands, thresholds),
+		Metrics:    compareMetrics(base.Metrics, head.Metrics, thresholds),
+	}
+	if base.Status == JobSucceeded && head.Status != "" && head.Status != JobSucceeded {
+		cmp.Regressions = append(cmp.Regressions, "the job "+string(head.Status))
+	}
+	for _, c := range cmp.Commands {
+		if c.Regression {
+			cmp.Regressions = append(cmp.Regressions, fmt.Sprintf("command %d: %s", c.Index, c.RegressionNote))
+		}
+	}
+	for _, m := range cmp.Metrics {
+		if m.Regression {
+			cmp.Regressions = append(cmp.Regressions, m.String())
+		}
+	}
+	return cmp
+}
+
+// String renders the comparison as a table
+func (c JobComparison) String() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "base %s (%s) → head %s (%s)\n", c.Base, c.BaseStatus, c.Head, c.HeadStatus)
+
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	if len(c.Commands) != 0 {
+		fmt.Fprintln(w, "#\tCOMMAND\tBASE\tHEAD\t\t")
+		for _, cmd := range c.Commands {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t\n", cmd.Index, truncateCommand(cmd.Command),
+				comparedCommand(cmd.BaseStatus, cmd.BaseDuration), comparedCommand(cmd.HeadStatus, cmd.HeadDuration),
+				regressionMark(cmd.Regression))
+		}
+	}
+	if len(c.Metrics) != 0 {
+		fmt.Fprintln(w, "\tMETRIC\tBASE\tHEAD\t\t")
+		for _, m := range c.Metrics {
+			base, head := "-", "-"
+			if m.HasOld {
+				base = formatMetricValue(m.Old, m.Unit)
+			}
+			if m.HasNew {
+				head = formatMetricValue(m.New, m.Unit)
+			}
+			fmt.Fprintf(w, "\t%s\t%s\t%s\t%s\t\n", m.Name, base, head, regressionMark(m.Regression))
+		}
+	}
+	w.Flush()
+
+	if c.Regressed() {
+		fmt.Fprintf(buf, "%d regression(s) detected\n", len(c.Regressions))
+	} else {
+		fmt.Fprintln(buf, "no regression detected")
+	}
+	return buf.String()
+}
+
+func comparedCommand(status CommandStatus, d time.Duration) string {
+	switch status {
+	case "":
+		return "-"
+	case CommandPassed:
+		return d.Round(time.Millisecond).String()
+	default:
+		return string(status)
+	}
+}
+
+func regressionMark(regression bool) string {
+	if regression {
+		return "REGRESSION"
+	}
+	return ""
+}
+
+// LoadJob returns the job from the local history at historyPath (the
+// default history if empty) or, if it is not found there, from the
+// rankings collection
+func LoadJob(historyPath, jobID string) (HistoryEntry, error) {
+	entry, err := OpenHistory(historyPath).Show(jobID)
+	if err == nil {
+		return entry, nil
+	}
+	ranked, rankedErr := loadRankedJob(jobID)
+	if rankedErr == nil {
+		return ranked, nil
+	}
+	log.WithError(rankedErr).Debug("job not found in the rankings collection")
+	return HistoryEntry{}, err
+}
+
+// CompareJobIDs loads the two jobs and compares them using the
+// thresholds of the client config
+func CompareJobIDs(historyPath, baseID, headID string) (JobComparison, error) {
+	base, err := LoadJob(historyPath, baseID)
+	if err != nil {
+		return JobComparison{}, err
+	}
+	head, err := LoadJob(historyPath, headID)
+	if err != nil {
+		return JobComparison{}, err
+	}
+	return CompareJobs(base, head, DefaultRegressionThresholds()), nil
+}
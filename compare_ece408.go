@@ -0,0 +1,50 @@
+// +build ece408ProjectMode
+
+package client
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rai-project/config"
+	"github.com/rai-project/database/mongodb"
+	"gopkg.in/mgo.v2/bson"
+	upper "upper.io/db.v3"
+)
+
+// loadRankedJob returns the job recorded in the rankings collection
+func loadRankedJob(jobID string) (HistoryEntry, error) {
+	if !bson.IsObjectIdHex(jobID) {
+		return HistoryEntry{}, errors.Errorf("%v is not a valid job id", jobID)
+	}
+
+	db, err := mongodb.NewDatabase(config.App.Name)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	defer db.Close()
+
+	col, err := NewEce408JobResponseBodyCollection(db)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	defer col.Close()
+
+	var bodies Ece408JobResponseBodys
+	err = col.Find(upper.Cond{"_id": bson.ObjectIdHex(jobID)}, 0, 1, &bodies)
+	if err != nil {
+		return HistoryEntry{}, errors.Wrapf(err, "unable to find job %v in the rankings", jobID)
+	}
+	if len(bodies) == 0 {
+		return HistoryEntry{}, errors.Errorf("job %v is not in the rankings", jobID)
+	}
+
+	body := bodies[0]
+	entry := HistoryEntry{
+		JobID:     body.ID.Hex(),
+		CreatedAt: body.ID.Time(),
+		Metrics:   ece408Metrics(&body),
+	}
+	if body.SubmissionTag != "" {
+		entry.Labels = []string{body.SubmissionTag}
+	}
+	return entry, nil
+}
@@ -0,0 +1,10 @@
+// +build !ece408ProjectMode
+
+package client
+
+import "github.com/pkg/errors"
+
+// loadRankedJob returns the job recorded in the rankings collection
+func loadRankedJob(jobID string) (HistoryEntry, error) {
+	return HistoryEntry{}, errors.New("the rankings are only available in the ece408 project mode")
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareJobs(t *testing.T) {
+	thresholds := RegressionThresholds{
+		CommandDuration:    0.2,
+		MinCommandDuration: time.Second,
+		Metric:             0.05,
+		Metrics:            map[string]float64{"*.elapsed": 0.5},
+	}
+	base := HistoryEntry{
+		JobID:  "base",
+		Status: JobSucceeded,
+		Commands: []CommandResult{
+			{Index: 0, Command: "make", Status: CommandPassed, Duration: 10 * time.Second},
+			{Index: 1, Command: "./run", Status: CommandPassed, Duration: 100 * time.Millisecond},
+		},
+		Metrics: Metrics{
+			{Name: "inference.0.correctness", Value: 0.8673},
+			{Name: "inference.0.op_time", Value: 0.100, Unit: "s"},
+			{Name: "inference.0.elapsed", Value: 1.0, Unit: "s"},
+		},
+	}
+
+	cmp := CompareJobs(base, base, thresholds)
+	assert.False(t, cmp.Regressed())
+	assert.NoError(t, cmp.Err())
+	assert.Equal(t, 0, cmp.ExitCode())
+	assert.Contains(t, cmp.String(), "no regression detected")
+
+	head := base
+	head.JobID = "head"
+	head.Commands = []CommandResult{
+		{Index: 0, Command: "make", Status: CommandPassed, Duration: 11 * time.Second},
+		{Index: 1, Command: "./run", Status: CommandPassed, Duration: 500 * time.Millisecond},
+	}
+	head.Metrics = Metrics{
+		{Name: "inference.0.correctness", Value: 0.8673},
+		{Name: "inference.0.op_time", Value: 0.090, Unit: "s"},
+		{Name: "inference.0.elapsed", Value: 1.4, Unit: "s"},
+	}
+	cmp = CompareJobs(base, head, thresholds)
+	assert.False(t, cmp.Regressed(), "improvements and changes within the thresholds are not regressions")
+
+	head.Commands = []CommandResult{
+		{Index: 0, Command: "make", Status: CommandPassed, Duration: 13 * time.Second},
+		{Index: 1, Command: "./run", Status: CommandFailed, Duration: 100 * time.Millisecond},
+	}
+	head.Metrics = Metrics{
+		{Name: "inference.0.correctness", Value: 0.1},
+		{Name: "inference.0.op_time", Value: 0.110, Unit: "s"},
+	}
+	head.Status = JobFailed
+	cmp = CompareJobs(base, head, thresholds)
+	assert.True(t, cmp.Regressed())
+	assert.Equal(t, 1, cmp.ExitCode())
+	assert.Equal(t, ErrRegression, errors.Cause(cmp.Err()))
+	assert.Len(t, cmp.Regressions, 5)
+	assert.True(t, cmp.Commands[0].Regression)
+	assert.True(t, cmp.Commands[1].Regression)
+	assert.Equal(t, 1, cmp.Commands[1].Index, "the index of the command tracker is kept")
+	for _, m := range cmp.Metrics {
+		assert.Equal(t, m.Name != "inference.0.elapsed", m.Regression, m.Name)
+	}
+	assert.Contains(t, cmp.String(), "5 regression(s) detected")
+}
+
+func TestRegressionThresholdsMetric(t *testing.T) {
+	thresholds := RegressionThresholds{
+		Metric: 0.05,
+		Metrics: map[string]float64{
+			"inference.*":             0.1,
+			"*.elapsed":               0.5,
+			"inference.0.elapsed":     1,
+			"inference.*.correctness": 0,
+		},
+	}
+	for ii := 0; ii < 10; ii++ {
+		assert.Equal(t, 1.0, thresholds.metric("inference.0.elapsed"), "the exact name wins")
+		assert.Equal(t, 0.1, thresholds.metric("inference.1.elapsed"), "the longest pattern wins")
+		assert.Equal(t, 0.0, thresholds.metric("inference.1.correctness"))
+		assert.Equal(t, 0.05, thresholds.metric("command.0.duration"))
+	}
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"runtime"
+	"time"
 
 	"github.com/k0kubun/pp"
 	"github.com/rai-project/config"
@@ -9,12 +10,34 @@ import (
 )
 
 type clientConfig struct {
-	UploadBucketName           string        `json:"upload_bucket" config:"client.upload_bucket" default:"files.rai-project.com"`
-	UploadDestinationDirectory string        `json:"upload_destination_directory" config:"client.upload_destination_directory" default:"userdata"`
-	BuildFileBaseName          string        `json:"build_file" config:"client.build_file" default:"default"`
-	SubmitRequirements         []string      `json:"submit_requirements" config:"client.submit_requirements"`
-	JobQueueName               string        `json:"job_queue_name" config:"client.job_queue_name"`
-	done                       chan struct{} `json:"-" config:"-"`
+	UploadBucketName            string                       `json:"upload_bucket" config:"client.upload_bucket" default:"files.rai-project.com"`
+	UploadDestinationDirectory  string                       `json:"upload_destination_directory" config:"client.upload_destination_directory" default:"userdata"`
+	BuildFileBaseName           string                       `json:"build_file" config:"client.build_file" default:"default"`
+	SubmitRequirements          []string                     `json:"submit_requirements" config:"client.submit_requirements"`
+	JobQueueName                string                       `json:"job_queue_name" config:"client.job_queue_name"`
+	QueueRoutes                 []QueueRoute                 `json:"queue_routes" config:"client.queue_routes"`
+	AWSCredentialsLifetime      time.Duration                `json:"aws_credentials_lifetime" config:"client.aws_credentials_lifetime" default:"1h"`
+	AWSCredentialsRefreshWindow time.Duration                `json:"aws_credentials_refresh_window" config:"client.aws_credentials_refresh_window" default:"5m"`
+	Deployment                  string                       `json:"deployment" config:"client.deployment"`
+	Deployments                 map[string]Deployment        `json:"deployments" config:"client.deployments"`
+	Registries                  map[string]DockerCredentials `json:"registries" config:"client.registries"`
+	Notifiers                   []NotifierConfig             `json:"notifiers" config:"client.notifiers"`
+	NotifyTimeout               time.Duration                `json:"notify_timeout" config:"client.notify_timeout" default:"2m"`
+	BatchConcurrency            int                          `json:"batch_concurrency" config:"client.batch_concurrency" default:"4"`
+	ControlChannel              string                       `json:"control_channel" config:"client.control_channel"`
+	CancelTimeout               time.Duration                `json:"cancel_timeout" config:"client.cancel_timeout" default:"30s"`
+	InterruptPolicy             string                       `json:"interrupt_policy" config:"client.interrupt_policy" default:"leave"`
+	InterruptGracePeriod        time.Duration                `json:"interrupt_grace_period" config:"client.interrupt_grace_period" default:"5s"`
+	WatchPolicy                 string                       `json:"watch_policy" config:"client.watch_policy" default:"cancel"`
+	WatchDebounce               time.Duration                `json:"watch_debounce" config:"client.watch_debounce" default:"1s"`
+	WatchIgnore                 []string                     `json:"watch_ignore" config:"client.watch_ignore"`
+	HistoryPath                 string                       `json:"history_path" config:"client.history_path"`
+	RegressionCommandDuration   float64                      `json:"regression_command_duration" config:"client.regression_command_duration" default:"0.2"`
+	RegressionMinDuration       time.Duration                `json:"regression_min_duration" config:"client.regression_min_duration" default:"1s"`
+	RegressionMetric            float64                      `json:"regression_metric" config:"client.regression_metric" default:"0.05"`
+	RegressionMetrics           map[string]float64           `json:"regression_metrics" config:"client.regression_metrics"`
+	OutputParsers               []OutputParserConfig         `json:"output_parsers" config:"client.output_parsers"`
+	done                        chan struct{}                `json:"-" config:"-"`
 }
 
 // Config ...
@@ -42,10 +65,15 @@ func (a *clientConfig) Read() {
 		a.BuildFileBaseName = config.App.Name + "_build"
 	}
 	if a.JobQueueName == "" || a.JobQueueName == "default" {
-		a.JobQueueName = config.App.Name + "_" + runtime.GOARCH
+		a.JobQueueName = defaultJobQueueName()
 	}
 }
 
+// defaultJobQueueName is the job queue used unless one is configured
+func defaultJobQueueName() string {
+	return config.App.Name + "_" + runtime.GOARCH
+}
+
 // Wait ...
 func (c clientConfig) Wait() {
 	<-c.done
@@ -53,12 +81,12 @@ func (c clientConfig) Wait() {
 
 // String ...
 func (c clientConfig) String() string {
-	return pp.Sprintln(c)
+	return Secrets.Redact(pp.Sprintln(c))
 }
 
 // Debug ...
 func (c clientConfig) Debug() {
-	log.Debug("Client Config = ", c)
+	log.Debug("Client Config = ", c.String())
 }
 
 func init() {
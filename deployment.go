@@ -0,0 +1,165 @@
+package client
+
+import (
+	"sort"
+	"strings"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+	"github.com/rai-project/broker"
+	"github.com/rai-project/broker/rabbitmq"
+	"github.com/rai-project/broker/sqs"
+	"github.com/rai-project/pubsub"
+	"github.com/rai-project/pubsub/redis"
+	"github.com/rai-project/serializer/json"
+)
+
+const (
+	// BrokerKindSQS publishes jobs to an AWS SQS queue
+	BrokerKindSQS = "sqs"
+	// BrokerKindRabbitMQ publishes jobs to a RabbitMQ queue
+	BrokerKindRabbitMQ = "rabbitmq"
+	// DefaultDeploymentName is the deployment used when none is selected
+	DefaultDeploymentName = "aws"
+)
+
+// Deployment describes the backends of a RAI cluster. Deployments are
+// configured in the client config, for example
+//
+//	client:
+//	  deployments:
+//	    minio:
+//	      storage_endpoint: http://minio.example.edu:9000
+//	      path_style: true
+//	      sts: false
+//	      broker_kind: rabbitmq
+//	      broker_url: amqp://rabbitmq.example.edu:5672
+//	      architectures:
+//	        - s390x
+type Deployment struct {
+	Name            string   `json:"name" yaml:"name" mapstructure:"name"`
+	StorageEndpoint string   `json:"storage_endpoint" yaml:"storage_endpoint" mapstructure:"storage_endpoint"`
+	Region          string   `json:"region" yaml:"region" mapstructure:"region"`
+	PathStyle       bool     `json:"path_style" yaml:"path_style" mapstructure:"path_style"`
+	STS             bool     `json:"sts" yaml:"sts" mapstructure:"sts"`
+	BrokerKind      string   `json:"broker_kind" yaml:"broker_kind" mapstructure:"broker_kind"`
+	BrokerURL       string   `json:"broker_url" yaml:"broker_url" mapstructure:"broker_url"`
+	PubSubURL       string   `json:"pubsub_url" yaml:"pubsub_url" mapstructure:"pubsub_url"`
+	Architectures   []string `json:"architectures" yaml:"architectures" mapstructure:"architectures"`
+}
+
+// DefaultDeployments are the deployments known without any configuration.
+// They can be overridden by deployments with the same name in the config.
+var DefaultDeployments = map[string]Deployment{
+	DefaultDeploymentName: {
+		Region:     "us-east-1",
+		STS:        true,
+		BrokerKind: BrokerKindSQS,
+	},
+	"minio": {
+		Region:        "us-east-1",
+		STS:           false,
+		BrokerKind:    BrokerKindRabbitMQ,
+		Architectures: []string{"s390x"},
+	},
+}
+
+// Deployments returns the default deployments merged with the configured ones
+func Deployments() map[string]Deployment {
+	res := map[string]Deployment{}
+	for name, d := range DefaultDeployments {
+		d.Name = name
+		res[name] = d
+	}
+	for name, d := range Config.Deployments {
+		d.Name = name
+		res[name] = d
+	}
+	return res
+}
+
+// deployment selects the deployment from the option, the config, or the
+// server architecture in that order. When several deployments support
+// the server architecture the first one by name is selected.
+func (c *Client) deployment() (Deployment, error) {
+	deployments := Deployments()
+
+	name := c.options.deploymentName
+	if name == "" {
+		name = Config.Deployment
+	}
+	if name != "" {
+		d, ok := deployments[name]
+		if !ok {
+			return Deployment{}, errors.Errorf("the deployment %v is not configured", name)
+		}
+		return d, nil
+	}
+
+	if arch := c.options.serverArch; arch != "" {
+		names := []string{}
+		for name := range deployments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			d := deployments[name]
+			for _, a := range d.Architectures {
+				if strings.EqualFold(a, arch) {
+					return d, nil
+				}
+			}
+		}
+	}
+
+	return deployments[DefaultDeploymentName], nil
+}
+
+// storageSession returns the session used to access the upload bucket of
+// the deployment. The storage endpoint is only set on this copy of the
+// shared session, so that the other AWS services keep their endpoints.
+func (c *Client) storageSession(d Deployment) *session.Session {
+	cfg := awssdk.NewConfig()
+	if d.StorageEndpoint != "" {
+		cfg = cfg.WithEndpoint(d.StorageEndpoint)
+	}
+	if d.PathStyle {
+		cfg = cfg.WithS3ForcePathStyle(true)
+	}
+	return c.awsSession.Copy(cfg)
+}
+
+// newBroker creates the broker used to publish the job request
+func (c *Client) newBroker(d Deployment) (broker.Broker, error) {
+	opts := []broker.Option{}
+	if d.BrokerURL != "" {
+		opts = append(opts, broker.Endpoints([]string{d.BrokerURL}))
+	}
+	switch strings.ToLower(d.BrokerKind) {
+	case BrokerKindRabbitMQ:
+		opts = append(opts,
+			rabbitmq.QueueName(c.JobQueueName()),
+			broker.Serializer(json.New()),
+		)
+		return rabbitmq.New(opts...), nil
+	case BrokerKindSQS, "":
+		opts = append(opts,
+			sqs.QueueName(c.JobQueueName()),
+			broker.Serializer(c.serializer),
+			sqs.Session(c.awsSession),
+		)
+		return sqs.New(opts...)
+	default:
+		return nil, errors.Errorf("unsupported broker kind %v in the %v deployment", d.BrokerKind, d.Name)
+	}
+}
+
+// newPubSubConnection creates the connection used to receive the job logs
+func (c *Client) newPubSubConnection(d Deployment) (pubsub.Connection, error) {
+	opts := []pubsub.Option{}
+	if d.PubSubURL != "" {
+		opts = append(opts, pubsub.Endpoints([]string{d.PubSubURL}))
+	}
+	return redis.New(opts...)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeploymentSelection(t *testing.T) {
+	defer func(name string, deployments map[string]Deployment) {
+		Config.Deployment, Config.Deployments = name, deployments
+	}(Config.Deployment, Config.Deployments)
+	Config.Deployment = ""
+	Config.Deployments = map[string]Deployment{
+		"zcluster": {BrokerKind: BrokerKindRabbitMQ, Architectures: []string{"s390x"}},
+		"campus":   {BrokerKind: BrokerKindRabbitMQ, Architectures: []string{"ppc64le"}},
+	}
+
+	d, err := (&Client{}).deployment()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDeploymentName, d.Name)
+
+	// minio and zcluster both support s390x, the first by name wins
+	for ii := 0; ii < 10; ii++ {
+		d, err = (&Client{options: Options{serverArch: "S390X"}}).deployment()
+		require.NoError(t, err)
+		assert.Equal(t, "minio", d.Name)
+	}
+
+	d, err = (&Client{options: Options{serverArch: "ppc64le"}}).deployment()
+	require.NoError(t, err)
+	assert.Equal(t, "campus", d.Name)
+
+	Config.Deployment = "zcluster"
+	d, err = (&Client{options: Options{serverArch: "ppc64le"}}).deployment()
+	require.NoError(t, err)
+	assert.Equal(t, "zcluster", d.Name, "the configured deployment wins over the architecture")
+
+	d, err = (&Client{options: Options{deploymentName: "campus"}}).deployment()
+	require.NoError(t, err)
+	assert.Equal(t, "campus", d.Name, "the option wins over the config")
+
+	_, err = (&Client{options: Options{deploymentName: "unknown"}}).deployment()
+	assert.Error(t, err)
+}
@@ -0,0 +1,318 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/rai-project/auth"
+	"github.com/rai-project/config"
+	"github.com/rai-project/pubsub/redis"
+	"github.com/rai-project/serializer/json"
+	"github.com/rai-project/store"
+	"github.com/rai-project/store/s3"
+)
+
+// DiagnosticStatus ...
+type DiagnosticStatus string
+
+const (
+	DiagnosticOK      DiagnosticStatus = "ok"
+	DiagnosticFailed  DiagnosticStatus = "failed"
+	DiagnosticSkipped DiagnosticStatus = "skipped"
+)
+
+// DefaultDiagnosticTimeout bounds the pubsub round-trip check
+var DefaultDiagnosticTimeout = 10 * time.Second
+
+// diagnosticRepublishInterval is how often the pubsub round-trip message
+// is published until it is received
+const diagnosticRepublishInterval = 500 * time.Millisecond
+
+// DiagnosticCheck is the result of checking one backend
+type DiagnosticCheck struct {
+	Name    string           `json:"name"`
+	Status  DiagnosticStatus `json:"status"`
+	Latency time.Duration    `json:"latency"`
+	Message string           `json:"message,omitempty"`
+	Hint    string           `json:"hint,omitempty"`
+}
+
+// DiagnosticReport ...
+type DiagnosticReport struct {
+	ClientVersion string            `json:"client_version"`
+	Deployment    string            `json:"deployment"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Checks        []DiagnosticCheck `json:"checks"`
+}
+
+// diagnostic is a single named check. hint is shown to the user when the check fails.
+// The check is skipped if one of the checks it depends on did not pass.
+type diagnostic struct {
+	name    string
+	hint    string
+	depends []string
+	check   func(ctx context.Context) (string, error)
+}
+
+// OK returns true if none of the checks failed
+func (r DiagnosticReport) OK() bool {
+	for _, check := range r.Checks {
+		if check.Status == DiagnosticFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// JSON ...
+func (r DiagnosticReport) JSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// String returns the human readable report
+func (r DiagnosticReport) String() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "✱ Diagnostics for client %v using the %v deployment\n", r.ClientVersion, r.Deployment)
+	for _, check := range r.Checks {
+		switch check.Status {
+		case DiagnosticOK:
+			fmt.Fprint(buf, color.GreenString("  ✔ %-20s", check.Name))
+		case DiagnosticFailed:
+			fmt.Fprint(buf, color.RedString("  ✘ %-20s", check.Name))
+		default:
+			fmt.Fprint(buf, color.YellowString("  - %-20s", check.Name))
+		}
+		if check.Status != DiagnosticSkipped {
+			fmt.Fprintf(buf, " %8v", check.Latency.Round(time.Millisecond))
+		}
+		if check.Message != "" {
+			fmt.Fprint(buf, "  "+check.Message)
+		}
+		fmt.Fprintln(buf)
+		if check.Status == DiagnosticFailed && check.Hint != "" {
+			fmt.Fprintln(buf, color.YellowString("      hint: "+check.Hint))
+		}
+	}
+	return Secrets.Redact(buf.String())
+}
+
+// Diagnose checks every backend the client depends on. Checks that
+// depend on a failed check are skipped. The returned error is only
+// non-nil if the diagnosis itself could not be performed.
+func (c *Client) Diagnose(ctx context.Context) (*DiagnosticReport, error) {
+	deployment, err := c.deployment()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DiagnosticReport{
+		ClientVersion: config.App.Version,
+		Deployment:    deployment.Name,
+		CreatedAt:     time.Now(),
+	}
+
+	brokerDepends := []string{}
+	if kind := strings.ToLower(deployment.BrokerKind); kind == BrokerKindSQS || kind == "" {
+		brokerDepends = append(brokerDepends, "storage")
+	}
+
+	// the profile is only shared with the jobs of the client once verified
+	var prof auth.Profile
+	diagnostics := []diagnostic{
+		{
+			name: "profile",
+			hint: "check that " + c.profileDescription() + " exists and is a valid profile file",
+			check: func(ctx context.Context) (string, error) {
+				var err error
+				prof, err = c.newProfile()
+				if err != nil {
+					return "", err
+				}
+				return "parsed " + c.profileDescription(), nil
+			},
+		},
+		{
+			name: "credentials",
+			hint: "your access and secret keys were rejected. Download a new profile or contact the course staff",
+			check: func(ctx context.Context) (string, error) {
+				ok, err := prof.Verify()
+				if err != nil {
+					return "", err
+				}
+				if !ok {
+					return "", errors.New("the credentials could not be verified")
+				}
+				c.profile = prof
+				c.registerSecrets()
+				return "verified user " + c.profile.Info().Username, nil
+			},
+		},
+		{
+			name: "role",
+			hint: "make sure you are using the client for your course from http://github.com/rai-project/rai",
+			check: func(ctx context.Context) (string, error) {
+				role, err := c.profile.GetRole()
+				if err != nil {
+					return "", err
+				}
+				if err := c.validateUserRole(); err != nil {
+					return "", err
+				}
+				return "role " + string(role), nil
+			},
+		},
+		{
+			name: "storage",
+			hint: "the upload bucket " + Config.UploadBucketName + " could not be written to. Check your network connection and the storage endpoint of the deployment",
+			check: func(ctx context.Context) (string, error) {
+				if err := c.createAWSSession(); err != nil {
+					return "", err
+				}
+				st, err := s3.New(
+					s3.Session(c.storageSession(deployment)),
+					store.Bucket(Config.UploadBucketName),
+				)
+				if err != nil {
+					return "", err
+				}
+				key := Config.UploadDestinationDirectory + "/diagnose-" + c.ID.Hex()
+				key, err = st.UploadFrom(strings.NewReader(c.ID.Hex()), key)
+				if err != nil {
+					return "", errors.Wrap(err, "unable to write")
+				}
+				if err := st.Delete(key); err != nil {
+					return "", errors.Wrap(err, "unable to delete")
+				}
+				return "wrote and deleted " + key, nil
+			},
+		},
+		{
+			name: "broker",
+			hint: "the job queue " + c.JobQueueName() + " is not reachable. Check your network connection and the broker of the deployment",
+			// sqs uses the aws session created by the storage check
+			depends: brokerDepends,
+			check: func(ctx context.Context) (string, error) {
+				brkr, err := c.newBroker(deployment)
+				if err != nil {
+					return "", err
+				}
+				if err := brkr.Connect(); err != nil {
+					return "", err
+				}
+				defer brkr.Disconnect()
+				return "connected to " + deployment.BrokerKind + " queue " + c.JobQueueName(), nil
+			},
+		},
+		{
+			name: "pubsub",
+			hint: "job logs cannot be received. Check that outgoing connections to the pubsub server are allowed",
+			check: func(ctx context.Context) (string, error) {
+				return c.diagnosePubSub(ctx, deployment)
+			},
+		},
+	}
+	diagnostics = append(diagnostics, c.ece408Diagnostics()...)
+
+	failed := false
+	passed := map[string]bool{}
+	for _, d := range diagnostics {
+		if failed || ctx.Err() != nil {
+			report.Checks = append(report.Checks, DiagnosticCheck{
+				Name:   d.name,
+				Status: DiagnosticSkipped,
+			})
+			continue
+		}
+		if missing := missingDependencies(d.depends, passed); len(missing) != 0 {
+			report.Checks = append(report.Checks, DiagnosticCheck{
+				Name:    d.name,
+				Status:  DiagnosticSkipped,
+				Message: "depends on " + strings.Join(missing, ", "),
+			})
+			continue
+		}
+		start := time.Now()
+		msg, err := d.check(ctx)
+		check := DiagnosticCheck{
+			Name:    d.name,
+			Status:  DiagnosticOK,
+			Latency: time.Since(start),
+			Message: Secrets.Redact(msg),
+		}
+		if err != nil {
+			check.Status = DiagnosticFailed
+			check.Message = Secrets.Redact(err.Error())
+			check.Hint = d.hint
+			// the remaining checks depend on the profile and credentials
+			failed = d.name == "profile" || d.name == "credentials"
+		}
+		passed[d.name] = err == nil
+		report.Checks = append(report.Checks, check)
+	}
+
+	return report, nil
+}
+
+func missingDependencies(depends []string, passed map[string]bool) []string {
+	missing := []string{}
+	for _, name := range depends {
+		if !passed[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// diagnosePubSub subscribes to a diagnostic channel, publishes a message
+// on it, and waits for the message to be received
+func (c *Client) diagnosePubSub(ctx context.Context, deployment Deployment) (string, error) {
+	conn, err := c.newPubSubConnection(deployment)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	channel := config.App.Name + "/diagnose-" + c.ID.Hex()
+	subscriber, err := redis.NewSubscriber(conn, channel)
+	if err != nil {
+		return "", err
+	}
+	defer subscriber.Stop()
+	msgs := subscriber.Start()
+
+	publisher, err := redis.NewPublisher(conn)
+	if err != nil {
+		return "", err
+	}
+	defer publisher.Stop()
+
+	// the subscription may not be active yet when the first message is
+	// published, so the message is published again until it is received
+	if err := publisher.Publish(channel, c.ID.Hex()); err != nil {
+		return "", err
+	}
+	republish := time.NewTicker(diagnosticRepublishInterval)
+	defer republish.Stop()
+
+	timeout := time.NewTimer(DefaultDiagnosticTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-msgs:
+			return "round-trip on " + channel, nil
+		case <-republish.C:
+			if err := publisher.Publish(channel, c.ID.Hex()); err != nil {
+				return "", err
+			}
+		case <-timeout.C:
+			return "", errors.Errorf("no message received on %v after %v", channel, DefaultDiagnosticTimeout)
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,27 @@
+// +build ece408ProjectMode
+
+package client
+
+import (
+	"context"
+
+	"github.com/rai-project/config"
+	"github.com/rai-project/database/mongodb"
+)
+
+func (c *Client) ece408Diagnostics() []diagnostic {
+	return []diagnostic{
+		{
+			name: "submission database",
+			hint: "the submission database is unreachable. Submissions will fail until it is available; contact the course staff",
+			check: func(ctx context.Context) (string, error) {
+				db, err := mongodb.NewDatabase(config.App.Name)
+				if err != nil {
+					return "", err
+				}
+				defer db.Close()
+				return "connected to " + config.App.Name, nil
+			},
+		},
+	}
+}
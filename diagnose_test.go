@@ -0,0 +1,55 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/rai-project/serializer/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnosticReport(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	report := DiagnosticReport{
+		ClientVersion: "0.2.60",
+		Deployment:    "aws",
+		CreatedAt:     time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC),
+		Checks: []DiagnosticCheck{
+			{Name: "profile", Status: DiagnosticOK, Latency: 2 * time.Millisecond, Message: "parsed ~/.rai_profile"},
+			{Name: "storage", Status: DiagnosticFailed, Latency: time.Second, Message: "access denied", Hint: "check the bucket"},
+			{Name: "broker", Status: DiagnosticSkipped, Message: "depends on storage"},
+		},
+	}
+	assert.False(t, report.OK())
+
+	s := report.String()
+	assert.Contains(t, s, "client 0.2.60 using the aws deployment")
+	assert.Contains(t, s, "✔ profile")
+	assert.Contains(t, s, "2ms  parsed ~/.rai_profile")
+	assert.Contains(t, s, "✘ storage")
+	assert.Contains(t, s, "hint: check the bucket")
+	assert.Contains(t, s, "- broker")
+	assert.Contains(t, s, "depends on storage")
+	assert.NotContains(t, s, "hint: \n")
+
+	buf, err := report.JSON()
+	require.NoError(t, err)
+	var decoded DiagnosticReport
+	require.NoError(t, json.Unmarshal(buf, &decoded))
+	assert.Equal(t, report.Checks, decoded.Checks)
+	assert.Equal(t, "aws", decoded.Deployment)
+
+	report.Checks[1].Status = DiagnosticOK
+	assert.True(t, report.OK(), "skipped checks do not fail the report")
+}
+
+func TestMissingDependencies(t *testing.T) {
+	passed := map[string]bool{"profile": true, "storage": false}
+	assert.Empty(t, missingDependencies(nil, passed))
+	assert.Empty(t, missingDependencies([]string{"profile"}, passed))
+	assert.Equal(t, []string{"storage", "pubsub"}, missingDependencies([]string{"profile", "storage", "pubsub"}, passed))
+}
@@ -1,14 +1,18 @@
 package client
 
+import (
+	"github.com/pkg/errors"
+)
+
 // there are multiple place where one can specify the docker
 // credentials. this canonicalized the credentials and places
-// them into the spec's push credentials field
+// them into the spec's push credentials field. the credentials
+// are resolved for the registry host of the image and are looked
+// up, in order, in
+//   - the client config registries section
+//   - the user's profile (only for Docker Hub)
+//   - the docker config file (~/.docker/config.json)
 func (c *Client) fixDockerPushCredentials() (err error) {
-	profileInfo := c.profile.Info()
-	if profileInfo.DockerHub == nil {
-		return
-	}
-
 	buildImage := c.buildSpec.Commands.BuildImage
 	if buildImage == nil {
 		return
@@ -22,9 +26,64 @@ func (c *Client) fixDockerPushCredentials() (err error) {
 	if !push.Push {
 		return
 	}
-	if push.Credentials.Username == "" && push.Credentials.Password == "" {
-		push.Credentials.Username = profileInfo.DockerHub.Username
-		push.Credentials.Password = profileInfo.DockerHub.Password
+	if push.Credentials.Username != "" || push.Credentials.Password != "" {
+		return
+	}
+
+	image := buildImage.ImageName
+	if err := ValidateImageReference(image); err != nil {
+		return err
+	}
+
+	registry := dockerRegistryHost(image)
+	creds, source, err := c.registryCredentials(registry, image)
+	if err != nil {
+		return err
+	}
+	if creds == nil {
+		return &ValidationError{
+			Message: "no credentials found to push " + image + " to the " + registry + " registry. " +
+				"Add them to the push section of the build file, to the client registries configuration, or log in using `docker login " + registry + "`",
+		}
 	}
-	return
+
+	log.WithField("registry", registry).
+		WithField("source", source).
+		Debug("using push credentials")
+	push.Credentials.Username = creds.Username
+	push.Credentials.Password = creds.Password
+	Secrets.Add(creds.Password)
+	return nil
+}
+
+// registryCredentials returns the credentials for the registry along with
+// a description of where they were found
+func (c *Client) registryCredentials(registry, image string) (*DockerCredentials, string, error) {
+	for host, creds := range Config.Registries {
+		if normalizeDockerAuthKey(host) != registry {
+			continue
+		}
+		creds := creds
+		return &creds, "client config", nil
+	}
+
+	if registry == DefaultDockerRegistry && c.profile != nil {
+		profileInfo := c.profile.Info()
+		if profileInfo.DockerHub != nil {
+			return &DockerCredentials{
+				Username: profileInfo.DockerHub.Username,
+				Password: profileInfo.DockerHub.Password,
+			}, "profile", nil
+		}
+	}
+
+	creds, err := DockerConfigCredentials(image)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "unable to read the docker credentials for the %v registry", registry)
+	}
+	if creds != nil {
+		return creds, "docker config", nil
+	}
+
+	return nil, "", nil
 }
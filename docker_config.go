@@ -0,0 +1,188 @@
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultDockerRegistry is the registry used for images without a registry host
+	DefaultDockerRegistry = "docker.io"
+	// dockerHubAuthKey is the key docker uses for Docker Hub in the config file
+	dockerHubAuthKey = "https://index.docker.io/v1/"
+)
+
+// DockerCredentials ...
+type DockerCredentials struct {
+	Username string `json:"username" yaml:"username" mapstructure:"username"`
+	Password string `json:"password" yaml:"password" mapstructure:"password"`
+}
+
+// dockerConfigFile is the subset of ~/.docker/config.json used by the client
+type dockerConfigFile struct {
+	Auths       map[string]dockerAuthConfig `json:"auths"`
+	CredsStore  string                      `json:"credsStore"`
+	CredHelpers map[string]string           `json:"credHelpers"`
+}
+
+type dockerAuthConfig struct {
+	Auth     string `json:"auth"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// dockerConfigPath returns the location of the docker config file. The
+// DOCKER_CONFIG environment variable overrides the default ~/.docker directory.
+func dockerConfigPath() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json"), nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".docker", "config.json"), nil
+}
+
+func readDockerConfigFile(path string) (*dockerConfigFile, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg dockerConfigFile
+	if err := json.Unmarshal(buf, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse the docker config file %v", path)
+	}
+	return &cfg, nil
+}
+
+// dockerRegistryHost returns the registry host of an image reference.
+// Following docker's rules, the first component is a host only if it
+// contains a '.' or a ':' or is localhost.
+func dockerRegistryHost(image string) string {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return DefaultDockerRegistry
+	}
+	host := image[:i]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return DefaultDockerRegistry
+	}
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return DefaultDockerRegistry
+	}
+	return host
+}
+
+// normalizeDockerAuthKey strips the scheme and path from the keys in the auths section
+func normalizeDockerAuthKey(key string) string {
+	if key == dockerHubAuthKey {
+		return DefaultDockerRegistry
+	}
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	if i := strings.Index(key, "/"); i >= 0 {
+		key = key[:i]
+	}
+	return dockerRegistryHost(key + "/")
+}
+
+func (a dockerAuthConfig) credentials() (*DockerCredentials, error) {
+	if a.Username != "" || a.Password != "" {
+		return &DockerCredentials{Username: a.Username, Password: a.Password}, nil
+	}
+	if a.Auth == "" {
+		return nil, nil
+	}
+	buf, err := base64.StdEncoding.DecodeString(a.Auth)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid auth entry in the docker config file")
+	}
+	parts := strings.SplitN(string(buf), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid auth entry in the docker config file")
+	}
+	return &DockerCredentials{Username: parts[0], Password: parts[1]}, nil
+}
+
+// dockerCredentialHelper runs docker-credential-<helper> get for the registry host
+func dockerCredentialHelper(helper, host string) (*DockerCredentials, error) {
+	serverURL := host
+	if host == DefaultDockerRegistry {
+		serverURL = dockerHubAuthKey
+	}
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stdout.String() + " " + stderr.String())
+		if strings.Contains(msg, "credentials not found") {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "docker credential helper %v failed: %v", helper, msg)
+	}
+	var resp struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, errors.Wrapf(err, "invalid output from docker credential helper %v", helper)
+	}
+	return &DockerCredentials{Username: resp.Username, Password: resp.Secret}, nil
+}
+
+// credentials resolves the credentials for the registry host. Per registry
+// credential helpers take precedence over the credential store, which
+// takes precedence over the plain auths entries.
+func (cfg *dockerConfigFile) credentials(host string) (*DockerCredentials, error) {
+	if helper, ok := cfg.CredHelpers[host]; ok && helper != "" {
+		return dockerCredentialHelper(helper, host)
+	}
+	if host == DefaultDockerRegistry {
+		if helper, ok := cfg.CredHelpers[dockerHubAuthKey]; ok && helper != "" {
+			return dockerCredentialHelper(helper, host)
+		}
+	}
+	if cfg.CredsStore != "" {
+		creds, err := dockerCredentialHelper(cfg.CredsStore, host)
+		if err != nil || creds != nil {
+			return creds, err
+		}
+	}
+	for key, auth := range cfg.Auths {
+		if normalizeDockerAuthKey(key) != host {
+			continue
+		}
+		return auth.credentials()
+	}
+	return nil, nil
+}
+
+// DockerConfigCredentials returns the credentials for the registry of the
+// image from the user's docker config file. It returns nil if the file
+// does not exist or has no entry for the registry.
+func DockerConfigCredentials(image string) (*DockerCredentials, error) {
+	path, err := dockerConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := readDockerConfigFile(path)
+	if os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cfg.credentials(dockerRegistryHost(image))
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerRegistryHost(t *testing.T) {
+	assert.Equal(t, DefaultDockerRegistry, dockerRegistryHost("ubuntu"))
+	assert.Equal(t, DefaultDockerRegistry, dockerRegistryHost("webgpu/cudnn:8.0"))
+	assert.Equal(t, DefaultDockerRegistry, dockerRegistryHost("index.docker.io/webgpu/cudnn:8.0"))
+	assert.Equal(t, "ghcr.io", dockerRegistryHost("ghcr.io/org/img"))
+	assert.Equal(t, "localhost:5000", dockerRegistryHost("localhost:5000/img"))
+}
+
+func TestDockerConfigAuths(t *testing.T) {
+	cfg := &dockerConfigFile{
+		Auths: map[string]dockerAuthConfig{
+			dockerHubAuthKey:       {Auth: "dXNlcjpwYXNz"}, // user:pass
+			"https://ghcr.io/v2/":  {Username: "gh", Password: "token"},
+			"registry.example.edu": {},
+		},
+	}
+	creds, err := cfg.credentials(DefaultDockerRegistry)
+	require.NoError(t, err)
+	assert.Equal(t, &DockerCredentials{Username: "user", Password: "pass"}, creds)
+
+	creds, err = cfg.credentials("ghcr.io")
+	require.NoError(t, err)
+	assert.Equal(t, &DockerCredentials{Username: "gh", Password: "token"}, creds)
+
+	creds, err = cfg.credentials("quay.io")
+	require.NoError(t, err)
+	assert.Nil(t, creds)
+}
+
+func TestValidateImageReference(t *testing.T) {
+	assert.NoError(t, ValidateImageReference("webgpu/cudnn:8.0"))
+	assert.NoError(t, ValidateImageReference("ghcr.io/org/img"))
+	assert.NoError(t, ValidateImageReference("registry.example.edu:5000/ece408/img:latest"))
+	assert.Error(t, ValidateImageReference("WebGPU/cudnn"))
+	assert.Error(t, ValidateImageReference("webgpu/cudnn:"))
+	assert.Error(t, ValidateImageReference(""))
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"regexp"
+	"strings"
+)
+
+// the grammar follows github.com/docker/distribution/reference
+//
+//	reference := name [ ":" tag ] [ "@" digest ]
+//	name      := [domain '/'] path-component ['/' path-component]*
+var (
+	dockerDomainComponent = `(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])`
+	dockerDomain          = dockerDomainComponent + `(?:\.` + dockerDomainComponent + `)*(?::[0-9]+)?`
+	dockerPathComponent   = `[a-z0-9]+(?:(?:[._]|__|[-]*)[a-z0-9]+)*`
+	dockerName            = `(?:` + dockerDomain + `/)?` + dockerPathComponent + `(?:/` + dockerPathComponent + `)*`
+	dockerTag             = `[\w][\w.-]{0,127}`
+	dockerDigest          = `[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}`
+	dockerReferenceRe     = regexp.MustCompile(`^(` + dockerName + `)(?::(` + dockerTag + `))?(?:@(` + dockerDigest + `))?$`)
+)
+
+// ValidateImageReference checks that the image name is a valid docker image reference
+func ValidateImageReference(image string) error {
+	if image == "" {
+		return &ValidationError{
+			Message: "the image name must not be empty",
+		}
+	}
+	if len(image) > 255 {
+		return &ValidationError{
+			Message: "the image name " + image + " is longer than 255 characters",
+		}
+	}
+	if !dockerReferenceRe.MatchString(image) {
+		msg := "the image name " + image + " is not a valid docker image reference"
+		if strings.ToLower(image) != image {
+			msg += ". Repository names must be lowercase"
+		}
+		return &ValidationError{
+			Message: msg,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Unknwon/com"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+// DefaultDockerfileName is used when the build_image section does not specify a dockerfile
+const DefaultDockerfileName = "Dockerfile"
+
+// dockerfileInstruction is a single instruction in a Dockerfile
+type dockerfileInstruction struct {
+	Line    int
+	Command string
+	Args    []string
+}
+
+// knownArchitectures are the architecture names that can appear in an image tag
+var knownArchitectures = map[string]string{
+	"amd64":   "amd64",
+	"x86_64":  "amd64",
+	"ppc64le": "ppc64le",
+	"ppc64":   "ppc64le",
+	"s390x":   "s390x",
+	"arm64":   "arm64",
+	"aarch64": "arm64",
+}
+
+// parseDockerfile returns the instructions in the Dockerfile. Line
+// continuations are joined and comments are skipped.
+func parseDockerfile(r io.Reader) ([]dockerfileInstruction, error) {
+	res := []dockerfileInstruction{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	startLine := 0
+	current := ""
+	flush := func() {
+		fields := strings.Fields(current)
+		current = ""
+		if len(fields) == 0 {
+			return
+		}
+		res = append(res, dockerfileInstruction{
+			Line:    startLine,
+			Command: strings.ToUpper(fields[0]),
+			Args:    fields[1:],
+		})
+	}
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if current == "" {
+			startLine = lineNumber
+		}
+		if strings.HasSuffix(line, "\\") {
+			current += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		current += line
+		flush()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// the last line may end with a continuation
+	flush()
+	return res, nil
+}
+
+// imageArchitecture returns the architecture encoded in the image tag,
+// or an empty string if the tag does not mention one
+func imageArchitecture(image string) string {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	tag := image[i+1:]
+	for _, part := range strings.FieldsFunc(strings.ToLower(tag), func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	}) {
+		if arch, ok := knownArchitectures[part]; ok {
+			return arch
+		}
+	}
+	return ""
+}
+
+// copySources returns the local sources of an ADD or COPY instruction.
+// Remote urls and sources copied from other build stages are skipped.
+func copySources(inst dockerfileInstruction) []string {
+	args := []string{}
+	for _, arg := range inst.Args {
+		if strings.HasPrefix(arg, "--from=") {
+			return nil
+		}
+		if strings.HasPrefix(arg, "--") {
+			continue
+		}
+		args = append(args, arg)
+	}
+	// JSON form, e.g. COPY ["a", "b", "/dst"]
+	if len(args) > 0 && strings.HasPrefix(args[0], "[") {
+		joined := strings.Trim(strings.Join(args, " "), "[]")
+		args = []string{}
+		for _, arg := range strings.Split(joined, ",") {
+			args = append(args, strings.Trim(strings.TrimSpace(arg), `"`))
+		}
+	}
+	if len(args) < 2 {
+		return nil
+	}
+	res := []string{}
+	for _, src := range args[:len(args)-1] {
+		if !isLocalSource(src) {
+			continue
+		}
+		res = append(res, src)
+	}
+	return res
+}
+
+// scpLikeSourceRe matches the git sources of the form user@host:path
+var scpLikeSourceRe = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9._-]+:`)
+
+// isLocalSource returns false for the sources that cannot be checked
+// locally: urls, git repositories and paths using build arguments or
+// environment variables
+func isLocalSource(src string) bool {
+	return !strings.Contains(src, "$") &&
+		!strings.Contains(src, "://") &&
+		!scpLikeSourceRe.MatchString(src)
+}
+
+func isInsideDirectory(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// validateBuildImage checks the Dockerfile referenced by commands.build_image
+// before the project is uploaded
+func (c *Client) validateBuildImage() error {
+	buildImage := c.buildSpec.Commands.BuildImage
+	if buildImage == nil {
+		return nil
+	}
+
+	if buildImage.ImageName != "" {
+		if err := ValidateImageReference(buildImage.ImageName); err != nil {
+			return err
+		}
+	}
+
+	dir, err := filepath.Abs(c.options.directory)
+	if err != nil {
+		return err
+	}
+
+	dockerfile := buildImage.Dockerfile
+	if dockerfile == "" {
+		dockerfile = DefaultDockerfileName
+	}
+	if !filepath.IsAbs(dockerfile) {
+		dockerfile = filepath.Join(dir, dockerfile)
+	}
+	dockerfile = filepath.Clean(dockerfile)
+
+	if !isInsideDirectory(dir, dockerfile) {
+		return &ValidationError{
+			Message: "the dockerfile " + buildImage.Dockerfile + " must be inside the project directory " + dir,
+		}
+	}
+	if !com.IsFile(dockerfile) {
+		return &ValidationError{
+			Message: "the dockerfile " + dockerfile + " does not exist",
+		}
+	}
+
+	f, err := os.Open(dockerfile)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open %v", dockerfile)
+	}
+	defer f.Close()
+
+	instructions, err := parseDockerfile(f)
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse %v", dockerfile)
+	}
+
+	arch := c.buildSpec.Resources.CPU.Architecture
+	for _, inst := range instructions {
+		switch inst.Command {
+		case "FROM":
+			if len(inst.Args) == 0 {
+				continue
+			}
+			imageArch := imageArchitecture(inst.Args[0])
+			if imageArch == "" || arch == "" || imageArch == arch {
+				continue
+			}
+			fprintln(c.options.stdout, color.YellowString(
+				"✱ Warning the base image %s on line %d of %s is built for %s, but the build file requests the %s architecture",
+				inst.Args[0], inst.Line, filepath.Base(dockerfile), imageArch, arch,
+			))
+		case "ADD", "COPY":
+			for _, src := range copySources(inst) {
+				srcPath := filepath.Join(dir, src)
+				if !isInsideDirectory(dir, srcPath) {
+					return &ValidationError{
+						Message: "the source " + src + " of the " + inst.Command + " instruction must be inside the project directory " + dir,
+					}
+				}
+				// the source may be created by a build step, so it is
+				// only a warning
+				matches, err := filepath.Glob(srcPath)
+				if err != nil || len(matches) == 0 {
+					fprintln(c.options.stdout, color.YellowString(
+						"✱ Warning the source %s of the %s instruction on line %d of %s does not exist",
+						src, inst.Command, inst.Line, filepath.Base(dockerfile),
+					))
+				}
+			}
+		}
+	}
+
+	return nil
+}
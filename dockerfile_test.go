@@ -0,0 +1,51 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDockerfile(t *testing.T) {
+	instructions, err := parseDockerfile(strings.NewReader(`FROM webgpu/cuda:ppc64le-8.0-devel
+# a comment
+COPY --chown=root main.c \
+     /src/
+ADD http://example.com/file.tgz /usr/local
+`))
+	require.NoError(t, err)
+	require.Len(t, instructions, 3)
+	assert.Equal(t, "FROM", instructions[0].Command)
+	assert.Equal(t, 3, instructions[1].Line)
+	assert.Equal(t, []string{"main.c"}, copySources(instructions[1]))
+	assert.Empty(t, copySources(instructions[2]))
+
+	instructions, err = parseDockerfile(strings.NewReader("FROM ubuntu\nCOPY main.c \\\n  /src/ \\"))
+	require.NoError(t, err)
+	require.Len(t, instructions, 2, "the last instruction ends with a continuation")
+	assert.Equal(t, []string{"main.c", "/src/"}, instructions[1].Args)
+}
+
+func TestImageArchitecture(t *testing.T) {
+	assert.Equal(t, "ppc64le", imageArchitecture("webgpu/cuda:ppc64le-8.0-devel"))
+	assert.Equal(t, "amd64", imageArchitecture("illinoisimpact/ece408_mxnet_docker:amd64-gpu-latest"))
+	assert.Equal(t, "", imageArchitecture("localhost:5000/ubuntu"))
+	assert.Equal(t, "", imageArchitecture("ubuntu"))
+}
+
+func TestCopySourcesSkipsNonLocalSources(t *testing.T) {
+	instructions, err := parseDockerfile(strings.NewReader(`COPY ${SRC} /app
+ADD $ARCHIVE /
+ADD git@github.com:org/repo.git /src
+ADD https://example.com/file.tgz /usr/local
+COPY main.c include/ /src/
+`))
+	require.NoError(t, err)
+	require.Len(t, instructions, 5)
+	for _, inst := range instructions[:4] {
+		assert.Empty(t, copySources(inst), inst.Args)
+	}
+	assert.Equal(t, []string{"main.c", "include/"}, copySources(instructions[4]))
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// printDryRun prints the job request that would be posted to the queue.
+// The build specification may contain the docker push credentials, so
+// it is redacted even if stdout does not go through the redactor.
+func (c *Client) printDryRun() error {
+	deployment, err := c.deployment()
+	if err != nil {
+		return err
+	}
+	spec, err := yaml.Marshal(c.jobBuildSpec())
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal the build specification")
+	}
+	fprintln(c.options.stdout, color.YellowString("✱ Dry run. The project would be submitted with the following request."))
+	fprintln(c.options.stdout, Secrets.Redact("job id: "+c.ID.Hex()+"\n"+
+		"deployment: "+deployment.Name+"\n"+
+		"queue: "+c.JobQueueName()+"\n"+
+		"directory: "+c.options.directory+"\n"+
+		"build specification:\n"+string(spec)))
+	return nil
+}
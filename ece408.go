@@ -6,10 +6,8 @@ import (
 	"net/url"
 	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/acarl005/stripansi"
 	"github.com/rai-project/model"
 )
 
@@ -37,11 +35,11 @@ var (
 		"algorithm",
 	}
 
-	timeOutputRe    = regexp.MustCompile(`^([0-9]*\.?[0-9]+)user\s+([0-9]*\.?[0-9]+)system\s+([0-9]*:[0-9]*\.?[0-9]*)elapsed.+`)
-	programOutputRe = regexp.MustCompile(`Correctness: ([-+]?[0-9]*\.?[0-9]+)\s+Model: (.*)`)
-	opTimeOutputRe  = regexp.MustCompile(`Op Time: ([-+]?[0-9]*\.?[0-9]+)`)
+	timeOutputRe    = regexp.MustCompile(ece408TimePattern)
+	programOutputRe = regexp.MustCompile(ece408CorrectnessPattern)
+	opTimeOutputRe  = regexp.MustCompile(ece408OpTimePattern)
 	projectURLRe    = regexp.MustCompile(`✱ The build folder has been uploaded to (\s*\[+?\s*(\!?)\s*([a-z]*)\s*\|?\s*([a-z0-9\.\-_]*)\s*\]+?)?\s*([^\s]+)\s*\..*`)
-	newInferenceRe  = regexp.MustCompile(`Loading model... done\r\nNew Inference`)
+	newInferenceRe  = regexp.MustCompile(`Loading model... done\r?\nNew Inference`)
 )
 
 func parseNewInference(job *Ece408JobResponseBody, s string) {
@@ -108,10 +106,8 @@ func parseTimeOutput(job *Ece408JobResponseBody, s string) {
 	if err == nil {
 		job.CurrentInference().SystemFullRuntime = system
 	}
-	i := strings.Index(matches[3], ":")
-	elapsed, err := time.ParseDuration(matches[3][i+1:len(matches[3])-i+1] + "s")
-	if err == nil {
-		job.CurrentInference().ElapsedFullRuntime = elapsed
+	if elapsed, ok := parseMetricValue(matches[3]); ok {
+		job.CurrentInference().ElapsedFullRuntime = time.Duration(elapsed * float64(time.Second))
 	}
 }
 
@@ -132,28 +128,80 @@ func parseProjectURL(job *Ece408JobResponseBody, s string) {
 	return
 }
 
-func (c *Client) parseLine(s string) {
-	if c.jobBody == nil {
-		c.jobBody = &Ece408JobResponseBody{
-			ID: c.ID,
+// ece408RankingParser fills the ranking record of the job from its
+// output. The metrics are extracted by the built-in ece408 parser; the
+// ranking record needs code since the values are grouped by inference,
+// a "New Inference" line starting a new group, and the model name and
+// project url are strings.
+type ece408RankingParser struct {
+	body *Ece408JobResponseBody
+}
+
+func newEce408RankingParser(j *Job) OutputParser {
+	return &ece408RankingParser{
+		body: &Ece408JobResponseBody{
+			ID: j.ID,
 			ECE408Ranking: ECE408Ranking{
 				Base: model.Base{
 					CreatedAt: time.Now(),
 					UpdatedAt: time.Now(),
 				},
 			},
-		}
+		},
 	}
+}
 
+func (p *ece408RankingParser) Name() string {
+	return "ece408_ranking"
+}
+
+func (p *ece408RankingParser) ParseLine(s string) {
+	parseNewInference(p.body, s)
+	parseOpTimeOutput(p.body, s)
+	parseProgramOutput(p.body, s)
+	parseTimeOutput(p.body, s)
+	parseProjectURL(p.body, s)
+}
+
+// Metrics returns nothing, the metrics are extracted by the ece408 parser
+func (p *ece408RankingParser) Metrics() Metrics {
+	return nil
+}
+
+// Body returns the ranking record recorded by RecordJob
+func (p *ece408RankingParser) Body() interface{} {
+	return p.body
+}
+
+func init() {
+	RegisterOutputParser("ece408_ranking", newEce408RankingParser)
+}
+
+// projectURL returns the url the project was uploaded to, if any
+func (c *Client) projectURL() string {
 	body, ok := c.jobBody.(*Ece408JobResponseBody)
-	if !ok {
-		panic("invalid job type")
+	if !ok || body.ProjectURL == "--" {
+		return ""
 	}
+	return body.ProjectURL
+}
 
-	s = stripansi.Strip(s)
-	parseNewInference(body, s)  //c.jobBody.(*Ece408JobResponseBody), s)
-	parseOpTimeOutput(body, s)  //c.jobBody.(*Ece408JobResponseBody), s)
-	parseProgramOutput(body, s) //c.jobBody.(*Ece408JobResponseBody), s)
-	parseTimeOutput(body, s)    //c.jobBody.(*Ece408JobResponseBody), s)
-	parseProjectURL(body, s)    //c.jobBody.(*Ece408JobResponseBody), s)
+// ece408Metrics returns the metrics of a ranking record, named as the
+// metrics of the built-in ece408 parser so that a ranked job can be
+// compared with a job of the history
+func ece408Metrics(body *Ece408JobResponseBody) Metrics {
+	metrics := Metrics{}
+	ops, elapsed := 0, 0
+	for ii, inference := range body.Inferences {
+		metrics = append(metrics, Metric{Name: "correctness." + strconv.Itoa(ii), Value: inference.Correctness})
+		for _, t := range inference.OpRuntimes {
+			metrics = append(metrics, Metric{Name: "op_time." + strconv.Itoa(ops), Value: t.Seconds(), Unit: "s"})
+			ops++
+		}
+		if inference.ElapsedFullRuntime != 0 {
+			metrics = append(metrics, Metric{Name: "elapsed." + strconv.Itoa(elapsed), Value: inference.ElapsedFullRuntime.Seconds(), Unit: "s"})
+			elapsed++
+		}
+	}
+	return metrics
 }
@@ -2,6 +2,10 @@
 
 package client
 
-func (c *Client) parseLine(s string) {
-	return
+func (c *Client) submissionKindName() string {
+	return ""
+}
+
+func (c *Client) projectURL() string {
+	return ""
 }
@@ -49,3 +49,11 @@ func SubmissionName(s0 string) Option {
 		o.ctx = context.WithValue(o.ctx, uploadExpirationKey{}, SubmissionUploadExpiration())
 	}
 }
+
+func (c *Client) submissionKindName() string {
+	s, ok := c.options.ctx.Value(submissionKindKey{}).(submissionKind)
+	if !ok {
+		return ""
+	}
+	return string(s)
+}
@@ -1,41 +1,40 @@
-//+ build ece408ProjectMode
+// +build ece408ProjectMode
 
 package client
 
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-
-	"github.com/rai-project/model"
 )
 
 func TestParse(t *testing.T) {
 	s := "✱ The build folder has been uploaded to http://s3.amazonaws.com/rai-server/uploads%2F629mfvXRR.tar.bz2. The data will be present for only a short duration of time."
 
-	ranking := &model.Ece408ResponseBody{}
+	ranking := &Ece408JobResponseBody{}
 	require.True(t, projectURLRe.MatchString(s))
-	parseLine(ranking, s)
-	assert.NotEmpty(t, ranking)
+	parseProjectURL(ranking, s)
+	assert.Equal(t, "http://s3.amazonaws.com/rai-server/uploads%2F629mfvXRR.tar.bz2", ranking.ProjectURL)
 }
 
 func TestParseTimeResult(t *testing.T) {
-	s := "4.85user 2.97system 5.25elapsed 148%CPU (0avgtext+0avgdata 1319488maxresident)"
+	s := "4.85user 2.97system 0:05.25elapsed 148%CPU (0avgtext+0avgdata 1319488maxresident)"
 
-	ranking := &model.Ece408ResponseBody{}
+	ranking := &Ece408JobResponseBody{}
 	ranking.StartNewInference()
 	require.True(t, timeOutputRe.MatchString(s))
 	parseTimeOutput(ranking, s)
 	fmt.Println(ranking)
-	assert.NotEmpty(t, ranking)
+	assert.Equal(t, 5250*time.Millisecond, ranking.CurrentInference().ElapsedFullRuntime)
 }
 
 func TestParseProgramOutput(t *testing.T) {
 	s := "Correctness: 0.1 Model: blah-wh@tever$/ugh"
 
-	ranking := &model.Ece408ResponseBody{}
+	ranking := &Ece408JobResponseBody{}
 	ranking.StartNewInference()
 	require.True(t, programOutputRe.MatchString(s))
 	parseProgramOutput(ranking, s)
@@ -45,9 +44,9 @@ func TestParseProgramOutput(t *testing.T) {
 }
 
 func TestParseNewRanking(t *testing.T) {
-	s := "[New Inference]"
+	s := "Loading model... done\nNew Inference"
 
-	ranking := &model.Ece408ResponseBody{}
+	ranking := &Ece408JobResponseBody{}
 	require.True(t, newInferenceRe.MatchString(s))
 	parseNewInference(ranking, s)
 	parseNewInference(ranking, s)
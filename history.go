@@ -0,0 +1,247 @@
+package client
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/rai-project/config"
+	"github.com/rai-project/model"
+	"github.com/rai-project/serializer/json"
+)
+
+// HistoryEntry is a job recorded in the local history
+type HistoryEntry struct {
+	JobID              string                   `json:"job_id"`
+	CreatedAt          time.Time                `json:"created_at"`
+	Duration           time.Duration            `json:"duration,omitempty"`
+	Directory          string                   `json:"directory"`
+	ArchiveHash        string                   `json:"archive_hash,omitempty"`
+	Labels             []string                 `json:"labels,omitempty"`
+	Queue              string                   `json:"queue,omitempty"`
+	Status             JobStatus                `json:"status"`
+	BuildSpecification model.BuildSpecification `json:"build_specification"`
+	Commands           []CommandResult          `json:"commands,omitempty"`
+	Metrics            Metrics                  `json:"metrics,omitempty"`
+	TranscriptPath     string                   `json:"transcript_path,omitempty"`
+}
+
+// HasLabel ...
+func (e HistoryEntry) HasLabel(label string) bool {
+	for _, l := range e.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// HistoryFilter selects the entries returned by History.List. The zero
+// value selects every entry.
+type HistoryFilter struct {
+	Label     string
+	Status    JobStatus
+	Directory string
+	Since     time.Time
+	Until     time.Time
+	// Limit keeps the most recent entries only
+	Limit int
+}
+
+// Match returns true if the entry is selected by the filter
+func (f HistoryFilter) Match(e HistoryEntry) bool {
+	if f.Label != "" && !e.HasLabel(f.Label) {
+		return false
+	}
+	if f.Status != "" && e.Status != f.Status {
+		return false
+	}
+	if f.Directory != "" && filepath.Clean(f.Directory) != filepath.Clean(e.Directory) {
+		return false
+	}
+	if !f.Since.IsZero() && e.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.CreatedAt.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// History is the local record of the submitted jobs. It is stored as
+// one JSON object per line so that entries are only ever appended.
+type History struct {
+	mu   sync.Mutex
+	path string
+}
+
+// DefaultHistoryPath is the path of the history unless one is configured
+func DefaultHistoryPath() string {
+	if Config.HistoryPath != "" {
+		if path, err := homedir.Expand(Config.HistoryPath); err == nil {
+			return path
+		}
+		return Config.HistoryPath
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	return filepath.Join(home, "."+config.App.Name, "history.jsonl")
+}
+
+// OpenHistory returns the history stored at path, or at the default
+// path if path is empty. The file is created on the first append.
+func OpenHistory(path string) *History {
+	if path == "" {
+		path = DefaultHistoryPath()
+	}
+	return &History{path: path}
+}
+
+// Path ...
+func (h *History) Path() string {
+	return h.path
+}
+
+// Append records the entry
+func (h *History) Append(e HistoryEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal the history entry")
+	}
+	// the build specification may contain registry credentials
+	buf = []byte(Secrets.Redact(string(buf)))
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return errors.Wrapf(err, "unable to create the history directory")
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open the history %v", h.path)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(buf, '\n')); err != nil {
+		return errors.Wrapf(err, "unable to write to the history %v", h.path)
+	}
+	return nil
+}
+
+func (h *History) read() ([]HistoryEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open the history %v", h.path)
+	}
+	defer f.Close()
+
+	entries := []HistoryEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e HistoryEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			log.WithError(err).Debug("skipping invalid history entry")
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read the history %v", h.path)
+	}
+	return entries, nil
+}
+
+// List returns the entries selected by the filter, oldest first
+func (h *History) List(filter HistoryFilter) ([]HistoryEntry, error) {
+	entries, err := h.read()
+	if err != nil {
+		return nil, err
+	}
+	selected := []HistoryEntry{}
+	for _, e := range entries {
+		if filter.Match(e) {
+			selected = append(selected, e)
+		}
+	}
+	sort.SliceStable(selected, func(ii, jj int) bool {
+		return selected[ii].CreatedAt.Before(selected[jj].CreatedAt)
+	})
+	if filter.Limit > 0 && len(selected) > filter.Limit {
+		selected = selected[len(selected)-filter.Limit:]
+	}
+	return selected, nil
+}
+
+// Show returns the entry of the job. A unique prefix of the job ID is
+// accepted.
+func (h *History) Show(jobID string) (HistoryEntry, error) {
+	entries, err := h.read()
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	matches := []HistoryEntry{}
+	for _, e := range entries {
+		if e.JobID == jobID {
+			return e, nil
+		}
+		if jobID != "" && strings.HasPrefix(e.JobID, jobID) {
+			matches = append(matches, e)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return HistoryEntry{}, errors.Errorf("job %v is not in the history", jobID)
+	case 1:
+		return matches[0], nil
+	default:
+		return HistoryEntry{}, errors.Errorf("the job id prefix %v is ambiguous", jobID)
+	}
+}
+
+// historyEntry describes the completed job
+func (c *Client) historyEntry() HistoryEntry {
+	e := HistoryEntry{
+		JobID:              c.ID.Hex(),
+		CreatedAt:          c.ID.Time(),
+		Directory:          c.options.directory,
+		ArchiveHash:        c.archiveHash,
+		Labels:             c.options.labels,
+		Queue:              c.JobQueueName(),
+		Status:             c.Status(),
+		BuildSpecification: c.buildSpec,
+		Commands:           c.CommandResults(),
+		Metrics:            c.Metrics(),
+		TranscriptPath:     c.TranscriptPath(),
+	}
+	if !c.publishedAt.IsZero() {
+		e.CreatedAt = c.publishedAt
+		e.Duration = time.Since(c.publishedAt)
+	}
+	return e
+}
+
+// recordHistory appends the job to the local history
+func (c *Client) recordHistory() {
+	if c.options.disableHistory {
+		return
+	}
+	if err := OpenHistory(c.options.historyPath).Append(c.historyEntry()); err != nil {
+		log.WithError(err).Error("failed to record the job in the history")
+	}
+}
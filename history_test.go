@@ -0,0 +1,84 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	history := OpenHistory(filepath.Join(dir, "history.jsonl"))
+
+	entries, err := history.List(HistoryFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	day := time.Date(2018, 4, 2, 12, 0, 0, 0, time.UTC)
+	for _, e := range []HistoryEntry{
+		{JobID: "5ac21a0f1a2b3c4d5e6f7081", CreatedAt: day.Add(time.Hour), Status: JobFailed, Labels: []string{"tiled"}},
+		{JobID: "5ac21a0f1a2b3c4d5e6f7080", CreatedAt: day, Status: JobSucceeded, Labels: []string{"baseline"},
+			Metrics: Metrics{{Name: "inference.0.op_time", Value: 0.020, Unit: "s"}}},
+		{JobID: "5ac3000f1a2b3c4d5e6f7082", CreatedAt: day.AddDate(0, 0, 1), Status: JobSucceeded, Labels: []string{"tiled", "unrolled"}},
+	} {
+		require.NoError(t, history.Append(e))
+	}
+
+	entries, err = history.List(HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "5ac21a0f1a2b3c4d5e6f7080", entries[0].JobID, "entries are sorted by date")
+
+	entries, err = history.List(HistoryFilter{Label: "tiled", Status: JobSucceeded})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "5ac3000f1a2b3c4d5e6f7082", entries[0].JobID)
+
+	entries, err = history.List(HistoryFilter{Until: day.Add(2 * time.Hour), Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "5ac21a0f1a2b3c4d5e6f7081", entries[0].JobID)
+
+	e, err := history.Show("5ac21a0f1a2b3c4d5e6f7080")
+	require.NoError(t, err)
+	assert.Equal(t, Metrics{{Name: "inference.0.op_time", Value: 0.020, Unit: "s"}}, e.Metrics)
+
+	e, err = history.Show("5ac3")
+	require.NoError(t, err)
+	assert.Equal(t, "5ac3000f1a2b3c4d5e6f7082", e.JobID)
+
+	_, err = history.Show("5ac2")
+	assert.Error(t, err, "ambiguous prefix")
+	_, err = history.Show("ffff")
+	assert.Error(t, err)
+}
+
+func TestFailedJobHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.jsonl")
+
+	base := &Client{connection: newConnection()}
+	Labels("baseline")(&base.options)
+	c := base.newJobClient(HistoryPath(path), Labels("tiled"))
+	c.setStatus(JobUploading)
+	c.Job.fail(errors.New("failed to upload the job"))
+
+	entries, err := OpenHistory(path).List(HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, c.ID.Hex(), entries[0].JobID)
+	assert.Equal(t, JobFailed, entries[0].Status)
+	assert.Equal(t, []string{"baseline", "tiled"}, entries[0].Labels)
+	assert.Equal(t, []string{"baseline"}, base.options.labels, "the labels of the client are not modified")
+}
@@ -13,5 +13,6 @@ var (
 func init() {
 	config.AfterInit(func() {
 		log = logger.New().WithField("pkg", "client")
+		log.Logger.AddHook(redactHook{})
 	})
 }
@@ -0,0 +1,422 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/rai-project/auth"
+	"github.com/rai-project/broker"
+	"github.com/rai-project/database"
+	"github.com/rai-project/model"
+	"github.com/rai-project/pubsub"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// JobStatus ...
+type JobStatus string
+
+const (
+	// JobPending means the job has not been uploaded yet
+	JobPending JobStatus = "pending"
+	// JobUploading means the project directory is being uploaded
+	JobUploading JobStatus = "uploading"
+	// JobQueued means the job request has been posted to the queue
+	JobQueued JobStatus = "queued"
+	// JobRunning means the server is sending the job output
+	JobRunning JobStatus = "running"
+	// JobSucceeded means every build command passed
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed means a build command failed or the job could not be completed
+	JobFailed JobStatus = "failed"
+	// JobCanceled means the job was cancelled on the server
+	JobCanceled JobStatus = "canceled"
+	// JobInterrupted means the client was interrupted before the job completed
+	JobInterrupted JobStatus = "interrupted"
+)
+
+// connection is the state shared by all the jobs submitted by a client
+type connection struct {
+	mu          sync.Mutex
+	awsSession  *session.Session
+	mongodb     database.Database
+	brokers     map[string]broker.Broker
+	pubsubConns map[string]pubsub.Connection
+	profile     auth.Profile
+	jobs        map[bson.ObjectId]*Job
+	interrupted chan struct{}
+	// notifications are the notifications being sent
+	notifications sync.WaitGroup
+}
+
+func newConnection() *connection {
+	return &connection{
+		brokers:     map[string]broker.Broker{},
+		pubsubConns: map[string]pubsub.Connection{},
+		jobs:        map[bson.ObjectId]*Job{},
+	}
+}
+
+// Job is a single submission of a project directory
+type Job struct {
+	ID                    bson.ObjectId
+	client                *Client
+	mu                    sync.Mutex
+	status                JobStatus
+	logs                  *jobLogs
+	waitOnce              sync.Once
+	waitErr               error
+	uploadKey             string
+	buildSpec             model.BuildSpecification
+	lintSuppress          []string
+	lintWarnings          LintWarnings
+	specMigration         *SpecMigrationResult
+	spinner               *spinner.Spinner
+	buildFileJobQueueName string
+	job                   *model.JobResponse
+	jsonLines             *jsonLinesEncoder
+	commandTracker        *commandTracker
+	specFilePath          string
+	transcript            *transcript
+	jobBody               interface{}
+	parsers               []OutputParser
+	publishedAt           time.Time
+	archiveHash           string
+	subscribers           []pubsub.Subscriber
+	cancelAck             chan CancelOutcome
+	finished              chan struct{}
+	done                  chan bool
+}
+
+// newJob creates a job for the client and makes it the job of the client
+func newJob(c *Client) *Job {
+	j := &Job{
+		ID:       bson.NewObjectId(),
+		client:   c,
+		status:   JobPending,
+		logs:     newJobLogs(),
+		finished: make(chan struct{}),
+		// buffered so that the output handler does not block
+		// if the job is abandoned before it completes
+		done: make(chan bool, 1),
+	}
+	c.Job = j
+
+	c.options.stdout, c.options.stderr = c.stdout, c.stderr
+
+	// in json lines mode everything is written as json
+	// objects to stdout and colors are disabled
+	if c.options.outputFormat == JSONLinesOutput {
+		color.NoColor = true
+		j.jsonLines = &jsonLinesEncoder{w: c.stdout, jobID: j.ID.Hex()}
+		c.options.stdout = &jsonLinesWriter{enc: j.jsonLines, stream: "stdout"}
+		c.options.stderr = &jsonLinesWriter{enc: j.jsonLines, stream: "stderr"}
+	}
+
+	c.connection.mu.Lock()
+	c.connection.jobs[j.ID] = j
+	c.connection.mu.Unlock()
+	return j
+}
+
+// Status returns the status of the job
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+func (j *Job) setStatus(s JobStatus) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status = s
+}
+
+// Logs returns the output of the job. The output received before the
+// call is replayed, so the logs can be read after Submit returns. The
+// channel is closed once the job completes.
+func (j *Job) Logs() <-chan model.JobResponse {
+	return j.logs.stream()
+}
+
+// Wait waits until the job completes
+func (j *Job) Wait() error {
+	j.waitOnce.Do(func() {
+		if j.client.options.dryRun {
+			// nothing was posted to the queue
+			j.logs.close()
+			j.closeFinished()
+		} else {
+			j.waitErr = j.client.wait()
+			j.stopSubscribers()
+		}
+
+		c := j.client.connection
+		c.mu.Lock()
+		delete(c.jobs, j.ID)
+		c.mu.Unlock()
+	})
+	return j.waitErr
+}
+
+// abort releases the job after a failed submission
+func (j *Job) abort() {
+	j.setStatus(JobFailed)
+	j.stopSubscribers()
+	j.logs.close()
+	j.closeFinished()
+
+	c := j.client.connection
+	c.mu.Lock()
+	delete(c.jobs, j.ID)
+	c.mu.Unlock()
+}
+
+// fail releases the job after a failed submission, records it in the
+// history and reports the failure to the notifiers
+func (j *Job) fail(err error) {
+	j.abort()
+	c := j.client
+	c.recordHistory()
+	c.notify(c.jobNotification(err))
+}
+
+// closeFinished signals that no more output will be received
+func (j *Job) closeFinished() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	select {
+	case <-j.finished:
+	default:
+		close(j.finished)
+	}
+}
+
+// Result returns the result of the job. It is complete once Wait returns.
+func (j *Job) Result() JobResult {
+	return j.client.Result()
+}
+
+// stopSubscribers stops receiving the job output
+func (j *Job) stopSubscribers() {
+	j.mu.Lock()
+	subscribers := j.subscribers
+	j.subscribers = nil
+	j.mu.Unlock()
+	for _, sub := range subscribers {
+		sub.Stop()
+	}
+}
+
+// jobLogs keeps the output of a job so that it can be streamed to
+// any number of readers
+type jobLogs struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	responses []model.JobResponse
+	closed    bool
+}
+
+func newJobLogs() *jobLogs {
+	l := &jobLogs{}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *jobLogs) append(resp model.JobResponse) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.responses = append(l.responses, resp)
+	l.cond.Broadcast()
+}
+
+func (l *jobLogs) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	l.cond.Broadcast()
+}
+
+func (l *jobLogs) stream() <-chan model.JobResponse {
+	ch := make(chan model.JobResponse)
+	go func() {
+		defer close(ch)
+		for next := 0; ; next++ {
+			l.mu.Lock()
+			for next >= len(l.responses) && !l.closed {
+				l.cond.Wait()
+			}
+			if next >= len(l.responses) {
+				l.mu.Unlock()
+				return
+			}
+			resp := l.responses[next]
+			l.mu.Unlock()
+			ch <- resp
+		}
+	}()
+	return ch
+}
+
+// newJobClient returns a client for a new job that shares the
+// connection of c. opts override the options of c for the job.
+func (c *Client) newJobClient(opts ...Option) *Client {
+	options := c.options
+	options.stdout, options.stderr = c.stdout, c.stderr
+
+	overrides := Options{}
+	for _, o := range opts {
+		o(&options)
+		o(&overrides)
+	}
+
+	clnt := &Client{
+		connection:          c.connection,
+		options:             options,
+		serializer:          c.serializer,
+		stdout:              c.stdout,
+		stderr:              c.stderr,
+		configJobQueueName:  c.configJobQueueName,
+		optionsJobQueueName: options.jobQueueName,
+	}
+	if overrides.stdout != nil {
+		clnt.stdout = newRedactWriter(overrides.stdout)
+	}
+	if overrides.stderr != nil {
+		clnt.stderr = newRedactWriter(overrides.stderr)
+	}
+	newJob(clnt)
+	return clnt
+}
+
+// submit validates, uploads and publishes the job. beforePublish, if
+// not nil, is called right before the job request is posted.
+func (c *Client) submit(ctx context.Context, beforePublish func() error) error {
+	// the steps, such as Upload and Publish, follow the context of the
+	// submission while the job keeps the context of the client
+	clientCtx := c.options.ctx
+	ctx, cancel := withClientContext(ctx, clientCtx)
+	defer cancel()
+	c.options.ctx = ctx
+	defer func() {
+		c.options.ctx = clientCtx
+	}()
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"validate", c.Validate},
+		{"authenticate", c.Authenticate},
+		{"upload", c.Upload},
+		{"subscribe", c.Subscribe},
+		{"publish", func() error {
+			if beforePublish != nil {
+				if err := beforePublish(); err != nil {
+					return err
+				}
+			}
+			return c.Publish()
+		}},
+	}
+	if c.options.dryRun {
+		steps = append(steps[:2], struct {
+			name string
+			run  func() error
+		}{"print", c.printDryRun})
+	}
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := step.run(); err != nil {
+			// errors may contain credentials
+			return redactError(errors.Wrapf(err, "failed to %s the job", step.name))
+		}
+	}
+	return nil
+}
+
+// submissionContext is the context of a submission. It carries the
+// values of the context of the client, such as the upload expiration.
+type submissionContext struct {
+	context.Context
+	values context.Context
+}
+
+func (ctx submissionContext) Value(key interface{}) interface{} {
+	if v := ctx.values.Value(key); v != nil {
+		return v
+	}
+	return ctx.Context.Value(key)
+}
+
+// withClientContext returns a context that is done when either ctx or
+// the context of the client, which is cancelled by HandleSignals, is done
+func withClientContext(ctx, clientCtx context.Context) (context.Context, context.CancelFunc) {
+	if clientCtx == nil {
+		clientCtx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-clientCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return submissionContext{Context: ctx, values: clientCtx}, cancel
+}
+
+// Submit submits a new job over the connections of the client and
+// returns once the job request has been posted to the queue. The
+// options, such as Directory or BuildFilePath, override the options
+// of the client for this job only. The client can be used to submit
+// any number of jobs, concurrently or not.
+func (c *Client) Submit(ctx context.Context, opts ...Option) (*Job, error) {
+	clnt := c.newJobClient(opts...)
+	if err := clnt.submit(ctx, nil); err != nil {
+		clnt.Job.fail(err)
+		return clnt.Job, err
+	}
+	return clnt.Job, nil
+}
+
+// sharedBroker returns the broker used to publish to the job queue,
+// creating and connecting it on first use
+func (c *Client) sharedBroker(d Deployment) (broker.Broker, error) {
+	// the same queue name may exist in several deployments
+	key := d.Name + "/" + c.JobQueueName()
+	c.connection.mu.Lock()
+	defer c.connection.mu.Unlock()
+	if brkr, ok := c.brokers[key]; ok {
+		return brkr, nil
+	}
+	brkr, err := c.newBroker(d)
+	if err != nil {
+		return nil, err
+	}
+	brkr.Connect()
+	c.brokers[key] = brkr
+	return brkr, nil
+}
+
+// sharedPubSubConnection returns the connection used to receive the
+// job logs of the deployment, creating it on first use
+func (c *Client) sharedPubSubConnection(d Deployment) (pubsub.Connection, error) {
+	c.connection.mu.Lock()
+	defer c.connection.mu.Unlock()
+	if conn, ok := c.pubsubConns[d.Name]; ok {
+		return conn, nil
+	}
+	conn, err := c.newPubSubConnection(d)
+	if err != nil {
+		return nil, err
+	}
+	c.pubsubConns[d.Name] = conn
+	return conn, nil
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rai-project/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobLogs(t *testing.T) {
+	logs := newJobLogs()
+	logs.append(model.JobResponse{Body: []byte("first")})
+
+	early := logs.stream()
+	assert.Equal(t, "first", string((<-early).Body))
+
+	logs.append(model.JobResponse{Body: []byte("second")})
+	logs.close()
+	assert.Equal(t, "second", string((<-early).Body))
+	_, ok := <-early
+	assert.False(t, ok)
+
+	// late readers get the whole output
+	bodies := []string{}
+	for resp := range logs.stream() {
+		bodies = append(bodies, string(resp.Body))
+	}
+	assert.Equal(t, []string{"first", "second"}, bodies)
+}
+
+func TestNewJobClient(t *testing.T) {
+	buf := new(bytes.Buffer)
+	base := &Client{
+		connection: newConnection(),
+		options:    Options{directory: "/projects/a"},
+		stdout:     nopWriterCloser{buf},
+		stderr:     nopWriterCloser{buf},
+	}
+	newJob(base)
+	assert.Equal(t, JobPending, base.Status())
+
+	clnt := base.newJobClient(Directory("/projects/b"))
+	assert.True(t, base.connection == clnt.connection)
+	assert.NotEqual(t, base.ID, clnt.ID)
+	assert.Equal(t, "/projects/a", base.options.directory)
+	assert.Equal(t, "/projects/b", clnt.options.directory)
+	assert.Len(t, base.jobs, 2)
+
+	clnt.Job.abort()
+	assert.Equal(t, JobFailed, clnt.Status())
+	assert.Len(t, base.jobs, 1)
+}
+
+func TestWithClientContext(t *testing.T) {
+	clientCtx, cancelClient := context.WithCancel(context.WithValue(context.Background(), uploadExpirationKey{}, "expiration"))
+	defer cancelClient()
+
+	ctx, cancel := withClientContext(context.Background(), clientCtx)
+	assert.Equal(t, "expiration", ctx.Value(uploadExpirationKey{}), "the values of the client are kept")
+	assert.NoError(t, ctx.Err())
+	cancel()
+	assert.Error(t, ctx.Err())
+	assert.NoError(t, clientCtx.Err(), "the client outlives the submission")
+
+	ctx, cancel = withClientContext(context.Background(), clientCtx)
+	defer cancel()
+	cancelClient()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the submission was not cancelled with the client")
+	}
+}
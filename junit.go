@@ -0,0 +1,143 @@
+package client
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+// junitFailureTailLines is the number of output lines included in a failure
+var junitFailureTailLines = 50
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	ID        string          `xml:"id,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Output  string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func tailLines(lines []string, n int) []string {
+	if len(lines) <= n {
+		return lines
+	}
+	return lines[len(lines)-n:]
+}
+
+// JUnitReport renders the command results as a JUnit XML document. Each
+// command is a test case of a suite named after the build file.
+func JUnitReport(suiteName, jobID string, results []CommandResult) ([]byte, error) {
+	suite := junitTestSuite{
+		Name:  suiteName,
+		Tests: len(results),
+		ID:    jobID,
+	}
+	var total time.Duration
+	for _, r := range results {
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("%02d %s", r.Index, r.Command),
+			ClassName: suiteName,
+			Time:      junitSeconds(r.Duration),
+			SystemOut: strings.Join(r.Output, "\n"),
+		}
+		switch r.Status {
+		case CommandFailed:
+			suite.Failures++
+			msg := "command failed"
+			if r.ExitCode != nil && *r.ExitCode >= 0 {
+				msg = fmt.Sprintf("command failed with exit code %d", *r.ExitCode)
+			}
+			tc.Failure = &junitFailure{
+				Message: msg,
+				Type:    "CommandFailure",
+				Output:  strings.Join(tailLines(r.Output, junitFailureTailLines), "\n"),
+			}
+		case CommandNotRun:
+			suite.Skipped++
+			tc.Skipped = &junitSkipped{
+				Message: "the command was not run",
+			}
+		}
+		if suite.Timestamp == "" && !r.StartedAt.IsZero() {
+			suite.Timestamp = r.StartedAt.Format("2006-01-02T15:04:05")
+		}
+		total += r.Duration
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Time = junitSeconds(total)
+
+	buf, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), buf...), nil
+}
+
+// specName is the name of the build file used for the job
+func (c *Client) specName() string {
+	if c.specFilePath != "" {
+		return strings.TrimSuffix(filepath.Base(c.specFilePath), filepath.Ext(c.specFilePath))
+	}
+	return c.options.buildFileBaseName
+}
+
+// writeJUnitReport writes the JUnit report if one was requested
+func (c *Client) writeJUnitReport() error {
+	path := c.options.junitReportPath
+	if path == "" || c.commandTracker == nil {
+		return nil
+	}
+	buf, err := JUnitReport(c.specName(), c.ID.Hex(), c.commandTracker.Results())
+	if err != nil {
+		return errors.Wrap(err, "unable to create the junit report")
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.Wrapf(err, "unable to create %v", dir)
+		}
+	}
+	if err := ioutil.WriteFile(path, buf, 0644); err != nil {
+		return errors.Wrapf(err, "unable to write the junit report to %v", path)
+	}
+	fprintln(c.options.stdout, color.GreenString("✱ The JUnit report has been written to "+path))
+	return nil
+}
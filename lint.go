@@ -0,0 +1,198 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rai-project/model"
+	"gopkg.in/yaml.v2"
+)
+
+// LintCategory groups lint rules by the kind of mistake they catch
+type LintCategory string
+
+const (
+	LintCategoryPaths     LintCategory = "paths"
+	LintCategoryRanking   LintCategory = "ranking"
+	LintCategoryCommands  LintCategory = "commands"
+	LintCategoryResources LintCategory = "resources"
+)
+
+// LintWarning is a non-fatal problem found in a build specification
+type LintWarning struct {
+	RuleID   string       `json:"rule_id"`
+	Category LintCategory `json:"category"`
+	Message  string       `json:"message"`
+	Command  string       `json:"command,omitempty"`
+}
+
+// LintWarnings ...
+type LintWarnings []LintWarning
+
+// LintRule is a single semantic check over a build specification
+type LintRule struct {
+	ID          string
+	Category    LintCategory
+	Description string
+	check       func(spec model.BuildSpecification) []string
+}
+
+// lintSuppression is the part of the build file that lists the
+// rules to suppress for that spec. For example
+//
+//	lint:
+//	  ignore:
+//	    - RAI003
+type lintSuppression struct {
+	Lint struct {
+		Ignore []string `yaml:"ignore"`
+	} `yaml:"lint"`
+}
+
+var (
+	hostPathRe      = regexp.MustCompile(`(^|[\s"'=])(\.\./|~/|/home/|/Users/|[A-Za-z]:\\)`)
+	rankingScriptRe = regexp.MustCompile(`python[0-9.]*\s+\S*(m[1-4]\.[0-9]+|final)\.py`)
+
+	// LintRules is the set of rules run by Lint
+	LintRules = []LintRule{
+		{
+			ID:          "RAI001",
+			Category:    LintCategoryPaths,
+			Description: "commands must only reference files inside /src; the host file system is not available on the server",
+			check:       lintHostPaths,
+		},
+		{
+			ID:          "RAI002",
+			Category:    LintCategoryRanking,
+			Description: "ranking runs must be prefixed with /usr/bin/time so that the runtime can be recorded",
+			check:       lintRankingTime,
+		},
+		{
+			ID:          "RAI003",
+			Category:    LintCategoryCommands,
+			Description: "each command runs in its own shell, so a standalone cd does not persist to the next command",
+			check:       lintStandaloneCd,
+		},
+		{
+			ID:          "RAI004",
+			Category:    LintCategoryResources,
+			Description: "a GPU architecture is requested but the image is a CPU-only image",
+			check:       lintGPUWithCPUImage,
+		},
+	}
+)
+
+// String ...
+func (w LintWarning) String() string {
+	if w.Command == "" {
+		return fmt.Sprintf("[%s] %s", w.RuleID, w.Message)
+	}
+	return fmt.Sprintf("[%s] %s (in `%s`)", w.RuleID, w.Message, w.Command)
+}
+
+// Lint runs all the lint rules over the build specification and returns
+// the warnings found. Rules whose IDs are in suppress are skipped.
+func Lint(spec model.BuildSpecification, suppress ...string) LintWarnings {
+	suppressed := map[string]bool{}
+	for _, id := range suppress {
+		suppressed[strings.ToUpper(strings.TrimSpace(id))] = true
+	}
+
+	warnings := LintWarnings{}
+	for _, rule := range LintRules {
+		if suppressed[rule.ID] {
+			continue
+		}
+		for _, cmd := range rule.check(spec) {
+			warnings = append(warnings, LintWarning{
+				RuleID:   rule.ID,
+				Category: rule.Category,
+				Message:  rule.Description,
+				Command:  cmd,
+			})
+		}
+	}
+	return warnings
+}
+
+// parseLintSuppression reads the list of suppressed rules from a build file
+func parseLintSuppression(buf []byte) []string {
+	var s lintSuppression
+	if err := yaml.Unmarshal(buf, &s); err != nil {
+		return nil
+	}
+	return s.Lint.Ignore
+}
+
+func lintHostPaths(spec model.BuildSpecification) []string {
+	res := []string{}
+	for _, cmd := range spec.Commands.Build {
+		if hostPathRe.MatchString(string(cmd)) {
+			res = append(res, string(cmd))
+		}
+	}
+	return res
+}
+
+func lintRankingTime(spec model.BuildSpecification) []string {
+	res := []string{}
+	for _, cmd := range spec.Commands.Build {
+		s := strings.TrimSpace(string(cmd))
+		if !rankingScriptRe.MatchString(s) {
+			continue
+		}
+		if strings.HasPrefix(s, "/usr/bin/time") {
+			continue
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+func lintStandaloneCd(spec model.BuildSpecification) []string {
+	res := []string{}
+	for _, cmd := range spec.Commands.Build {
+		s := strings.TrimSpace(string(cmd))
+		fields := strings.Fields(s)
+		if len(fields) == 0 || fields[0] != "cd" {
+			continue
+		}
+		if strings.ContainsAny(s, ";&|") {
+			continue
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+func lintGPUWithCPUImage(spec model.BuildSpecification) []string {
+	gpu := spec.Resources.GPU
+	if gpu == nil || (gpu.Architecture == "" && gpu.Count == 0) {
+		return nil
+	}
+	image := strings.ToLower(spec.RAI.ContainerImage)
+	if i := strings.LastIndex(image, ":"); i >= 0 {
+		image = image[i+1:]
+	}
+	if !strings.Contains(image, "cpu") {
+		return nil
+	}
+	return []string{""}
+}
+
+// lint prints the lint warnings for the current build specification.
+// Warnings never cause the validation to fail.
+func (c *Client) lint() LintWarnings {
+	warnings := Lint(c.buildSpec, c.lintSuppress...)
+	for _, w := range warnings {
+		fprintln(c.options.stdout, color.YellowString("✱ Warning "+w.String()))
+	}
+	return warnings
+}
+
+// LintWarnings returns the warnings found while validating the build specification
+func (c *Client) LintWarnings() LintWarnings {
+	return c.lintWarnings
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/rai-project/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func lintSpec(t *testing.T, s string) model.BuildSpecification {
+	var spec model.BuildSpecification
+	require.NoError(t, yaml.Unmarshal([]byte(s), &spec))
+	return spec
+}
+
+func TestLint(t *testing.T) {
+	spec := lintSpec(t, `
+rai:
+  version: 0.2
+  image: webgpu/rai:amd64-cpu
+resources:
+  cpu:
+    architecture: amd64
+  gpu:
+    architecture: volta
+    count: 1
+commands:
+  build:
+    - cd /build
+    - gcc ../main.c
+    - python m1.1.py
+    - /usr/bin/time python m1.2.py
+`)
+	warnings := Lint(spec)
+	ids := []string{}
+	for _, w := range warnings {
+		ids = append(ids, w.RuleID)
+	}
+	assert.Equal(t, []string{"RAI001", "RAI002", "RAI003", "RAI004"}, ids)
+}
+
+func TestLintSuppression(t *testing.T) {
+	buf := []byte(`
+lint:
+  ignore:
+    - rai003
+commands:
+  build:
+    - cd /build
+`)
+	suppress := parseLintSuppression(buf)
+	assert.Equal(t, []string{"rai003"}, suppress)
+	assert.Empty(t, Lint(lintSpec(t, string(buf)), suppress...))
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Metric is a value extracted from the output of a job
+type Metric struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// String ...
+func (m Metric) String() string {
+	return formatMetricValue(m.Value, m.Unit)
+}
+
+func formatMetricValue(v float64, unit string) string {
+	return strconv.FormatFloat(v, 'g', 6, 64) + unit
+}
+
+// Metrics ...
+type Metrics []Metric
+
+// Get returns the metric with the given name
+func (ms Metrics) Get(name string) (Metric, bool) {
+	for _, m := range ms {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Metric{}, false
+}
+
+// commandMetrics returns the duration of each tracked command
+func commandMetrics(results []CommandResult) Metrics {
+	metrics := Metrics{}
+	for _, r := range results {
+		if r.Status == CommandNotRun {
+			continue
+		}
+		metrics = append(metrics, Metric{
+			Name:  fmt.Sprintf("command.%d.duration", r.Index),
+			Value: r.Duration.Seconds(),
+			Unit:  "s",
+		})
+	}
+	return metrics
+}
+
+// Metrics returns the metrics of the job: the duration of the
+// tracked commands and the values parsed from the job output
+func (c *Client) Metrics() Metrics {
+	return append(commandMetrics(c.CommandResults()), c.parsedMetrics()...)
+}
+
+// MetricChange is the difference of a metric between two jobs
+type MetricChange struct {
+	Name   string  `json:"name"`
+	Unit   string  `json:"unit,omitempty"`
+	Old    float64 `json:"old"`
+	New    float64 `json:"new"`
+	HasOld bool    `json:"has_old"`
+	HasNew bool    `json:"has_new"`
+}
+
+// Relative returns the relative change of the metric, or NaN if the
+// metric is missing from one of the jobs or was zero
+func (ch MetricChange) Relative() float64 {
+	if !ch.HasOld || !ch.HasNew || ch.Old == 0 {
+		return math.NaN()
+	}
+	return (ch.New - ch.Old) / math.Abs(ch.Old)
+}
+
+// String renders the change as `name: old → new (+x%)`
+func (ch MetricChange) String() string {
+	switch {
+	case !ch.HasOld:
+		return ch.Name + ": (none) → " + formatMetricValue(ch.New, ch.Unit)
+	case !ch.HasNew:
+		return ch.Name + ": " + formatMetricValue(ch.Old, ch.Unit) + " → (none)"
+	}
+	s := ch.Name + ": " + formatMetricValue(ch.Old, ch.Unit) + " → " + formatMetricValue(ch.New, ch.Unit)
+	if rel := ch.Relative(); !math.IsNaN(rel) {
+		s += fmt.Sprintf(" (%+.1f%%)", rel*100)
+	}
+	return s
+}
+
+// DiffMetrics returns the metrics that changed between the two jobs, in
+// the order of the new job followed by the metrics that disappeared
+func DiffMetrics(old, new Metrics) []MetricChange {
+	changes := []MetricChange{}
+	for _, n := range new {
+		ch := MetricChange{Name: n.Name, Unit: n.Unit, New: n.Value, HasNew: true}
+		if o, ok := old.Get(n.Name); ok {
+			if o.Value == n.Value {
+				continue
+			}
+			ch.Old, ch.HasOld = o.Value, true
+		}
+		changes = append(changes, ch)
+	}
+	for _, o := range old {
+		if _, ok := new.Get(o.Name); !ok {
+			changes = append(changes, MetricChange{Name: o.Name, Unit: o.Unit, Old: o.Value, HasOld: true})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandMetrics(t *testing.T) {
+	metrics := commandMetrics([]CommandResult{
+		{Index: 1, Command: "make", Status: CommandPassed, Duration: 1500 * time.Millisecond},
+		{Index: 2, Command: "./ece408", Status: CommandNotRun},
+	})
+	assert.Equal(t, Metrics{{Name: "command.1.duration", Value: 1.5, Unit: "s"}}, metrics)
+}
+
+func TestDiffMetrics(t *testing.T) {
+	old := Metrics{
+		{Name: "op_time", Value: 0.020, Unit: "s"},
+		{Name: "correctness", Value: 0.8451},
+		{Name: "command.3.duration", Value: 4, Unit: "s"},
+	}
+	new := Metrics{
+		{Name: "op_time", Value: 0.018, Unit: "s"},
+		{Name: "correctness", Value: 0.8451},
+		{Name: "command.4.duration", Value: 2, Unit: "s"},
+	}
+
+	changes := DiffMetrics(old, new)
+	if assert.Len(t, changes, 3) {
+		assert.Equal(t, "op_time: 0.02s → 0.018s (-10.0%)", changes[0].String())
+		assert.Equal(t, "command.4.duration: (none) → 2s", changes[1].String())
+		assert.Equal(t, "command.3.duration: 4s → (none)", changes[2].String())
+	}
+	assert.InDelta(t, -0.1, changes[0].Relative(), 1e-9)
+}
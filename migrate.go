@@ -0,0 +1,258 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
+)
+
+// CurrentSpecVersion is the rai.version understood by model.BuildSpecification
+const CurrentSpecVersion = "0.2"
+
+// specMigration upgrades a build file from one rai.version to the next.
+// The migration works on the raw yaml document so that the key order
+// (and therefore the readability) of the file is preserved when it is
+// written back. It returns a human readable description of each change.
+type specMigration struct {
+	From    string
+	To      string
+	migrate func(doc yaml.MapSlice) (yaml.MapSlice, []string)
+}
+
+var specMigrations = []specMigration{
+	{
+		From:    "0.1",
+		To:      "0.2",
+		migrate: migrateSpec01To02,
+	},
+}
+
+// SpecMigrationResult ...
+type SpecMigrationResult struct {
+	FromVersion string
+	ToVersion   string
+	Changes     []string
+	Spec        []byte
+	// Warning is set if the build file was passed through unchanged
+	// because its version is unknown to the client
+	Warning string
+}
+
+// Migrated returns true if the build file was changed
+func (r SpecMigrationResult) Migrated() bool {
+	return r.FromVersion != r.ToVersion
+}
+
+// MigrateSpec upgrades the build file in buf to the CurrentSpecVersion.
+// Build files already at the current version, that do not specify a
+// version or whose version is unknown, such as a version newer than the
+// client, are returned unchanged.
+func MigrateSpec(buf []byte) (*SpecMigrationResult, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse build file")
+	}
+
+	version := specVersion(doc)
+	res := &SpecMigrationResult{
+		FromVersion: version,
+		ToVersion:   version,
+		Spec:        buf,
+	}
+	if version == CurrentSpecVersion || version == "" {
+		return res, nil
+	}
+
+	for version != CurrentSpecVersion {
+		var migration *specMigration
+		for ii := range specMigrations {
+			if specMigrations[ii].From == version {
+				migration = &specMigrations[ii]
+				break
+			}
+		}
+		if migration == nil && version == res.FromVersion {
+			// the server may understand a version this client does not
+			res.Warning = fmt.Sprintf("the build file uses rai.version %v which is unknown to this client. "+
+				"The current version is %v. The build file is sent unchanged", version, CurrentSpecVersion)
+			return res, nil
+		}
+		if migration == nil {
+			return nil, errors.Errorf("unsupported rai.version %v in build file. The current version is %v", version, CurrentSpecVersion)
+		}
+		var changes []string
+		doc, changes = migration.migrate(doc)
+		doc = setSpecVersion(doc, migration.To)
+		changes = append(changes, fmt.Sprintf("set rai.version from %v to %v", migration.From, migration.To))
+		res.Changes = append(res.Changes, changes...)
+		version = migration.To
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to serialize migrated build file")
+	}
+	res.ToVersion = version
+	res.Spec = out
+	return res, nil
+}
+
+// MigrateSpecFile upgrades the build file at path in place. The original
+// file is kept next to it with a .bak extension.
+func MigrateSpecFile(path string) (*SpecMigrationResult, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %v", path)
+	}
+	res, err := MigrateSpec(buf)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Migrated() {
+		return res, nil
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := ioutil.WriteFile(path+".bak", buf, perm); err != nil {
+		return nil, errors.Wrapf(err, "unable to backup %v", path)
+	}
+	if err := ioutil.WriteFile(path, res.Spec, perm); err != nil {
+		return nil, errors.Wrapf(err, "unable to write %v", path)
+	}
+	return res, nil
+}
+
+// migrateSpec upgrades the build file read by the client and prints
+// the changes that were made
+func (c *Client) migrateSpec(buf []byte) ([]byte, error) {
+	res, err := MigrateSpec(buf)
+	if err != nil {
+		return nil, err
+	}
+	if res.Warning != "" {
+		fprintln(c.options.stdout, color.YellowString("✱ Warning "+res.Warning+"."))
+	}
+	if !res.Migrated() {
+		return buf, nil
+	}
+	fprintln(c.options.stdout, color.YellowString(
+		"✱ Warning the build file uses rai.version %v. It has been upgraded to version %v with the following changes:",
+		res.FromVersion, res.ToVersion,
+	))
+	for _, change := range res.Changes {
+		fprintln(c.options.stdout, color.YellowString("    - "+change))
+	}
+	c.specMigration = res
+	return res.Spec, nil
+}
+
+// writeMigratedSpec writes the upgraded build file back to disk
+// if requested by the user
+func (c *Client) writeMigratedSpec(path string) error {
+	if c.specMigration == nil || !c.options.writeMigratedSpec {
+		return nil
+	}
+	if _, err := MigrateSpecFile(path); err != nil {
+		return err
+	}
+	fprintln(c.options.stdout, color.GreenString("✱ The upgraded build file has been written to "+path+". The original is in "+path+".bak"))
+	return nil
+}
+
+func specVersion(doc yaml.MapSlice) string {
+	rai, ok := mapSliceGet(doc, "rai").(yaml.MapSlice)
+	if !ok {
+		return ""
+	}
+	version := mapSliceGet(rai, "version")
+	if version == nil {
+		return ""
+	}
+	return cast.ToString(version)
+}
+
+func setSpecVersion(doc yaml.MapSlice, version string) yaml.MapSlice {
+	rai, _ := mapSliceGet(doc, "rai").(yaml.MapSlice)
+	rai = mapSliceSet(rai, "version", version)
+	return mapSliceSet(doc, "rai", rai)
+}
+
+// migrateSpec01To02 moves the 0.1 fields into the layout used by 0.2
+//   - the top level image is moved to rai.image
+//   - resources.architecture is moved to resources.cpu.architecture
+//   - resources.gpus is moved to resources.gpu.count
+//   - a commands list is moved to commands.build
+func migrateSpec01To02(doc yaml.MapSlice) (yaml.MapSlice, []string) {
+	changes := []string{}
+
+	if image := mapSliceGet(doc, "image"); image != nil {
+		rai, _ := mapSliceGet(doc, "rai").(yaml.MapSlice)
+		rai = mapSliceSet(rai, "image", image)
+		doc = mapSliceSet(doc, "rai", rai)
+		doc = mapSliceDelete(doc, "image")
+		changes = append(changes, "moved image to rai.image")
+	}
+
+	if resources, ok := mapSliceGet(doc, "resources").(yaml.MapSlice); ok {
+		if arch := mapSliceGet(resources, "architecture"); arch != nil {
+			cpu, _ := mapSliceGet(resources, "cpu").(yaml.MapSlice)
+			cpu = mapSliceSet(cpu, "architecture", arch)
+			resources = mapSliceSet(resources, "cpu", cpu)
+			resources = mapSliceDelete(resources, "architecture")
+			changes = append(changes, "moved resources.architecture to resources.cpu.architecture")
+		}
+		if gpus := mapSliceGet(resources, "gpus"); gpus != nil {
+			gpu, _ := mapSliceGet(resources, "gpu").(yaml.MapSlice)
+			gpu = mapSliceSet(gpu, "count", gpus)
+			resources = mapSliceSet(resources, "gpu", gpu)
+			resources = mapSliceDelete(resources, "gpus")
+			changes = append(changes, "moved resources.gpus to resources.gpu.count")
+		}
+		doc = mapSliceSet(doc, "resources", resources)
+	}
+
+	if commands, ok := mapSliceGet(doc, "commands").([]interface{}); ok {
+		doc = mapSliceSet(doc, "commands", yaml.MapSlice{
+			{Key: "build", Value: commands},
+		})
+		changes = append(changes, "moved the commands list to commands.build")
+	}
+
+	return doc, changes
+}
+
+func mapSliceGet(m yaml.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if cast.ToString(item.Key) == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+func mapSliceSet(m yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for ii, item := range m {
+		if cast.ToString(item.Key) == key {
+			m[ii].Value = value
+			return m
+		}
+	}
+	return append(m, yaml.MapItem{Key: key, Value: value})
+}
+
+func mapSliceDelete(m yaml.MapSlice, key string) yaml.MapSlice {
+	res := yaml.MapSlice{}
+	for _, item := range m {
+		if cast.ToString(item.Key) != key {
+			res = append(res, item)
+		}
+	}
+	return res
+}
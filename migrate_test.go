@@ -0,0 +1,63 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/rai-project/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMigrateSpec01(t *testing.T) {
+	buf := []byte(`
+rai:
+  version: 0.1
+image: webgpu/rai:root
+resources:
+  architecture: ppc64le
+  gpus: 1
+commands:
+  - echo "Building project"
+`)
+	res, err := MigrateSpec(buf)
+	require.NoError(t, err)
+	assert.True(t, res.Migrated())
+	assert.Equal(t, "0.1", res.FromVersion)
+	assert.Equal(t, CurrentSpecVersion, res.ToVersion)
+	assert.Len(t, res.Changes, 5)
+
+	var spec model.BuildSpecification
+	require.NoError(t, yaml.Unmarshal(res.Spec, &spec))
+	assert.Equal(t, "ppc64le", spec.Resources.CPU.Architecture)
+	assert.Equal(t, []string{`echo "Building project"`}, spec.Commands.Build)
+}
+
+func TestMigrateSpecCurrent(t *testing.T) {
+	buf := []byte(`
+rai:
+  version: 0.2
+commands:
+  build:
+    - echo "Building project"
+`)
+	res, err := MigrateSpec(buf)
+	require.NoError(t, err)
+	assert.False(t, res.Migrated())
+	assert.Equal(t, buf, res.Spec)
+}
+
+func TestMigrateSpecUnknownVersion(t *testing.T) {
+	buf := []byte(`
+rai:
+  version: 0.3
+commands:
+  build:
+    - echo "Building project"
+`)
+	res, err := MigrateSpec(buf)
+	require.NoError(t, err)
+	assert.False(t, res.Migrated())
+	assert.Equal(t, buf, res.Spec)
+	assert.Contains(t, res.Warning, "rai.version 0.3")
+}
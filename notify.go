@@ -0,0 +1,344 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rai-project/config"
+	"github.com/rai-project/serializer/json"
+)
+
+// The kinds of notifiers
+const (
+	NotifierWebhook = "webhook"
+	NotifierSMTP    = "smtp"
+	NotifierCommand = "command"
+)
+
+// DefaultNotifierTimeout bounds each notification attempt
+var DefaultNotifierTimeout = 30 * time.Second
+
+// NotifierConfig configures a notifier in the client config, for example
+//
+//	client:
+//	  notifiers:
+//	    - kind: webhook
+//	      url: https://hooks.example.edu/rai
+//	      events: [failed]
+//	      retries: 3
+//	    - kind: command
+//	      command: notify-send
+//	      args: ["RAI job finished"]
+type NotifierConfig struct {
+	Kind       string            `json:"kind" yaml:"kind" mapstructure:"kind"`
+	Events     []JobStatus       `json:"events" yaml:"events" mapstructure:"events"`
+	Queues     []string          `json:"queues" yaml:"queues" mapstructure:"queues"`
+	Retries    int               `json:"retries" yaml:"retries" mapstructure:"retries"`
+	RetryDelay time.Duration     `json:"retry_delay" yaml:"retry_delay" mapstructure:"retry_delay"`
+	URL        string            `json:"url" yaml:"url" mapstructure:"url"`
+	Headers    map[string]string `json:"headers" yaml:"headers" mapstructure:"headers"`
+	SMTPHost   string            `json:"smtp_host" yaml:"smtp_host" mapstructure:"smtp_host"`
+	SMTPPort   int               `json:"smtp_port" yaml:"smtp_port" mapstructure:"smtp_port"`
+	Username   string            `json:"username" yaml:"username" mapstructure:"username"`
+	Password   string            `json:"password" yaml:"password" mapstructure:"password"`
+	From       string            `json:"from" yaml:"from" mapstructure:"from"`
+	To         []string          `json:"to" yaml:"to" mapstructure:"to"`
+	Command    string            `json:"command" yaml:"command" mapstructure:"command"`
+	Args       []string          `json:"args" yaml:"args" mapstructure:"args"`
+}
+
+// Notification is the payload sent by the notifiers
+type Notification struct {
+	JobID      string        `json:"job_id"`
+	Status     JobStatus     `json:"status"`
+	Duration   time.Duration `json:"duration"`
+	ProjectURL string        `json:"project_url,omitempty"`
+	Queue      string        `json:"queue,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	FinishedAt time.Time     `json:"finished_at"`
+}
+
+// Notifier is notified when a job finishes or fails
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Accepts returns true if the notifier is interested in the notification
+func (cfg NotifierConfig) Accepts(n Notification) bool {
+	if len(cfg.Events) != 0 {
+		found := false
+		for _, e := range cfg.Events {
+			if strings.EqualFold(string(e), string(n.Status)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(cfg.Queues) != 0 {
+		for _, q := range cfg.Queues {
+			if matchRoutePattern(q, n.Queue) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// NewNotifier creates the notifier described by the config
+func NewNotifier(cfg NotifierConfig) (Notifier, error) {
+	switch strings.ToLower(cfg.Kind) {
+	case NotifierWebhook:
+		if cfg.URL == "" {
+			return nil, errors.New("the webhook notifier requires a url")
+		}
+		return &webhookNotifier{cfg}, nil
+	case NotifierSMTP:
+		if cfg.SMTPHost == "" || len(cfg.To) == 0 {
+			return nil, errors.New("the smtp notifier requires an smtp_host and at least one recipient")
+		}
+		return &smtpNotifier{cfg}, nil
+	case NotifierCommand:
+		if cfg.Command == "" {
+			return nil, errors.New("the command notifier requires a command")
+		}
+		return &commandNotifier{cfg}, nil
+	default:
+		return nil, errors.Errorf("unknown notifier kind %v", cfg.Kind)
+	}
+}
+
+type webhookNotifier struct {
+	cfg NotifierConfig
+}
+
+// Name ...
+func (w *webhookNotifier) Name() string {
+	return NotifierWebhook + " " + w.cfg.URL
+}
+
+// Notify posts the notification as JSON
+func (w *webhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", config.App.Name+"-client/"+config.App.Version)
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("the webhook returned %v", resp.Status)
+	}
+	return nil
+}
+
+type smtpNotifier struct {
+	cfg NotifierConfig
+}
+
+// Name ...
+func (s *smtpNotifier) Name() string {
+	return NotifierSMTP + " " + strings.Join(s.cfg.To, ",")
+}
+
+// Notify sends the notification as a plain text email
+func (s *smtpNotifier) Notify(ctx context.Context, n Notification) error {
+	port := s.cfg.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+	addr := s.cfg.SMTPHost + ":" + strconv.Itoa(port)
+	from := s.cfg.From
+	if from == "" {
+		from = s.cfg.Username
+	}
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.SMTPHost)
+	}
+
+	msg := new(bytes.Buffer)
+	fmt.Fprintf(msg, "From: %s\r\n", from)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(s.cfg.To, ", "))
+	fmt.Fprintf(msg, "Subject: [%s] job %s %s\r\n", config.App.Name, n.JobID, n.Status)
+	fmt.Fprintf(msg, "\r\n")
+	fmt.Fprintf(msg, "Job:      %s\r\n", n.JobID)
+	fmt.Fprintf(msg, "Status:   %s\r\n", n.Status)
+	fmt.Fprintf(msg, "Duration: %s\r\n", n.Duration.Round(time.Second))
+	if n.ProjectURL != "" {
+		fmt.Fprintf(msg, "Project:  %s\r\n", n.ProjectURL)
+	}
+	if n.Error != "" {
+		fmt.Fprintf(msg, "Error:    %s\r\n", n.Error)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- smtp.SendMail(addr, auth, from, s.cfg.To, msg.Bytes())
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type commandNotifier struct {
+	cfg NotifierConfig
+}
+
+// Name ...
+func (c *commandNotifier) Name() string {
+	return NotifierCommand + " " + c.cfg.Command
+}
+
+// Notify runs the command with the notification as JSON on its stdin
+// and in RAI_JOB_* environment variables
+func (c *commandNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	prefix := strings.ToUpper(config.App.Name) + "_JOB_"
+	cmd := exec.CommandContext(ctx, c.cfg.Command, c.cfg.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		prefix+"ID="+n.JobID,
+		prefix+"STATUS="+string(n.Status),
+		prefix+"DURATION="+n.Duration.String(),
+		prefix+"PROJECT_URL="+n.ProjectURL,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "%s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// notifyWithRetries sends the notification, retrying failed attempts
+// until ctx is done
+func notifyWithRetries(ctx context.Context, cfg NotifierConfig, notifier Notifier, n Notification) error {
+	delay := cfg.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	var err error
+	for attempt := 0; attempt <= cfg.Retries; attempt++ {
+		if attempt > 0 {
+			backoff := time.NewTimer(delay * time.Duration(attempt))
+			select {
+			case <-backoff.C:
+			case <-ctx.Done():
+				backoff.Stop()
+				return errors.Wrapf(err, "gave up after %d attempts", attempt)
+			}
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, DefaultNotifierTimeout)
+		err = notifier.Notify(attemptCtx, n)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		log.WithError(err).
+			WithField("notifier", notifier.Name()).
+			WithField("attempt", attempt+1).
+			Debug("failed to send the notification")
+	}
+	return err
+}
+
+// notify fires the configured notifiers for the job. The notifications
+// are sent in the background, each bounded by the notify_timeout config
+// value, so that slow notifiers and retries do not delay the caller.
+// Disconnect waits for the notifications in flight.
+func (c *Client) notify(n Notification) {
+	timeout := Config.NotifyTimeout
+	if timeout <= 0 {
+		timeout = DefaultNotifierTimeout
+	}
+	for _, cfg := range Config.Notifiers {
+		if !cfg.Accepts(n) {
+			continue
+		}
+		notifier, err := NewNotifier(cfg)
+		if err != nil {
+			log.WithError(err).Error("invalid notifier configuration")
+			continue
+		}
+		c.notifications.Add(1)
+		go func(cfg NotifierConfig, notifier Notifier) {
+			defer c.notifications.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := notifyWithRetries(ctx, cfg, notifier, n); err != nil {
+				log.WithError(err).
+					WithField("notifier", notifier.Name()).
+					Error("failed to send the notification")
+			}
+		}(cfg, notifier)
+	}
+}
+
+// jobNotification describes the outcome of the job. The status of the
+// job, such as failed when the submission failed or canceled, is kept.
+// Otherwise the job succeeded unless one of its tracked commands failed
+// or jobErr is not nil.
+func (c *Client) jobNotification(jobErr error) Notification {
+	n := Notification{
+		JobID:      c.ID.Hex(),
+		Status:     c.Status(),
+		ProjectURL: c.projectURL(),
+		Queue:      c.JobQueueName(),
+		FinishedAt: time.Now(),
+	}
+	if !c.publishedAt.IsZero() {
+		n.Duration = n.FinishedAt.Sub(c.publishedAt)
+	}
+	switch n.Status {
+	case JobFailed, JobCanceled, JobInterrupted:
+	default:
+		n.Status = JobSucceeded
+	}
+	for _, r := range c.CommandResults() {
+		if r.Status == CommandFailed {
+			n.Status = JobFailed
+			n.Error = "the command `" + r.Command + "` failed"
+		}
+	}
+	if jobErr != nil {
+		n.Error = Secrets.Redact(jobErr.Error())
+		switch {
+		case errors.Cause(jobErr) != ErrInterrupted:
+			n.Status = JobFailed
+		case n.Status != JobCanceled:
+			// the job may still be running on the server
+			n.Status = JobInterrupted
+		}
+	}
+	return n
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rai-project/serializer/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifierConfigAccepts(t *testing.T) {
+	n := Notification{JobID: "5b1a2c3d4e5f6a7b8c9d0e1f", Status: JobFailed, Queue: "rai_amd64_volta"}
+
+	assert.True(t, NotifierConfig{}.Accepts(n))
+	assert.True(t, NotifierConfig{Events: []JobStatus{"FAILED"}}.Accepts(n))
+	assert.False(t, NotifierConfig{Events: []JobStatus{JobSucceeded}}.Accepts(n))
+	assert.True(t, NotifierConfig{Queues: []string{"rai_amd64_*"}}.Accepts(n))
+	assert.False(t, NotifierConfig{Queues: []string{"rai_ppc64le*"}}.Accepts(n))
+}
+
+func TestNewNotifier(t *testing.T) {
+	_, err := NewNotifier(NotifierConfig{Kind: NotifierWebhook})
+	assert.Error(t, err)
+	_, err = NewNotifier(NotifierConfig{Kind: NotifierSMTP, SMTPHost: "smtp.example.edu"})
+	assert.Error(t, err)
+	_, err = NewNotifier(NotifierConfig{Kind: "pager"})
+	assert.Error(t, err)
+
+	notifier, err := NewNotifier(NotifierConfig{Kind: "Command", Command: "true"})
+	require.NoError(t, err)
+	assert.Equal(t, "command true", notifier.Name())
+}
+
+func TestWebhookNotifierRetries(t *testing.T) {
+	var received []Notification
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		buf, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var n Notification
+		require.NoError(t, json.Unmarshal(buf, &n))
+		received = append(received, n)
+	}))
+	defer server.Close()
+
+	cfg := NotifierConfig{
+		Kind:       NotifierWebhook,
+		URL:        server.URL,
+		Headers:    map[string]string{"X-Token": "secret"},
+		Retries:    2,
+		RetryDelay: time.Millisecond,
+	}
+	notifier, err := NewNotifier(cfg)
+	require.NoError(t, err)
+
+	n := Notification{
+		JobID:      "5b1a2c3d4e5f6a7b8c9d0e1f",
+		Status:     JobSucceeded,
+		Duration:   time.Minute,
+		ProjectURL: "http://s3.amazonaws.com/files.rai-project.com/project.tar.gz",
+	}
+	require.NoError(t, notifyWithRetries(context.Background(), cfg, notifier, n))
+	assert.Equal(t, 2, attempts)
+	require.Len(t, received, 1)
+	assert.Equal(t, n.JobID, received[0].JobID)
+	assert.Equal(t, n.ProjectURL, received[0].ProjectURL)
+
+	cfg.Retries = 0
+	attempts = 0
+	assert.Error(t, notifyWithRetries(context.Background(), cfg, notifier, n))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestNotifyWithRetriesGivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := NotifierConfig{Kind: NotifierWebhook, URL: server.URL, Retries: 100, RetryDelay: time.Hour}
+	notifier, err := NewNotifier(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Error(t, notifyWithRetries(ctx, cfg, notifier, Notification{JobID: "5b1a2c3d4e5f6a7b8c9d0e1f"}))
+	assert.True(t, time.Since(start) < time.Minute, "the backoff stops with the context")
+}
+
+func TestJobNotificationStatus(t *testing.T) {
+	c := &Client{connection: newConnection()}
+	newJob(c)
+
+	c.setStatus(JobRunning)
+	assert.Equal(t, JobSucceeded, c.jobNotification(nil).Status)
+
+	c.setStatus(JobCanceled)
+	assert.Equal(t, JobCanceled, c.jobNotification(nil).Status, "the status of the job is kept")
+
+	c.setStatus(JobUploading)
+	n := c.jobNotification(errors.New("failed to upload the job"))
+	assert.Equal(t, JobFailed, n.Status)
+	assert.Equal(t, "failed to upload the job", n.Error)
+
+	c.setStatus(JobRunning)
+	assert.Equal(t, JobInterrupted, c.jobNotification(ErrInterrupted).Status)
+	c.setStatus(JobCanceled)
+	assert.Equal(t, JobCanceled, c.jobNotification(ErrInterrupted).Status, "the interrupt cancelled the job")
+}
+
+func TestFailedBuildWithoutTrackingOption(t *testing.T) {
+	defer func(notifiers []NotifierConfig) { Config.Notifiers = notifiers }(Config.Notifiers)
+
+	for _, tc := range []struct {
+		name      string
+		opts      []Option
+		notifiers []NotifierConfig
+	}{
+		{name: "history"},
+		{name: "notifiers", opts: []Option{DisableHistory()}, notifiers: []NotifierConfig{{Kind: NotifierCommand, Command: "true"}}},
+	} {
+		Config.Notifiers = tc.notifiers
+		c := &Client{connection: newConnection()}
+		for _, o := range tc.opts {
+			o(&c.options)
+		}
+		newJob(c)
+		c.buildSpec.Commands.Build = append(c.buildSpec.Commands.Build, "make")
+		c.jobBuildSpec()
+		require.NotNil(t, c.commandTracker, tc.name)
+
+		start := time.Now()
+		c.commandTracker.process(trackerResponse(start, commandMarker()+" start 0"))
+		c.commandTracker.process(trackerResponse(start.Add(time.Second), commandMarker()+" exit 0 2"))
+		c.commandTracker.finish(start.Add(time.Second))
+		c.setStatus(JobRunning)
+		assert.Equal(t, JobFailed, c.jobNotification(nil).Status, tc.name)
+	}
+
+	Config.Notifiers = nil
+	c := &Client{connection: newConnection()}
+	DisableHistory()(&c.options)
+	newJob(c)
+	c.buildSpec.Commands.Build = append(c.buildSpec.Commands.Build, "make")
+	c.jobBuildSpec()
+	assert.Nil(t, c.commandTracker, "nothing reports the status of the job")
+}
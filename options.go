@@ -13,6 +13,7 @@ type Options struct {
 	buildFilePath        string
 	buildFileBaseName    string
 	profilePath          string
+	profileName          string
 	ratelimit            time.Duration
 	stdout               io.WriteCloser
 	stderr               io.WriteCloser
@@ -20,6 +21,22 @@ type Options struct {
 	outputDirectory      string
 	forceOutputDirectory bool
 	serverArch           string
+	deploymentName       string
+	outputFormat         OutputFormat
+	trackCommands        bool
+	junitReportPath      string
+	printCommandSummary  bool
+	recordTranscript     bool
+	transcriptPath       string
+	writeMigratedSpec    bool
+	interruptPolicy      InterruptPolicy
+	watchPolicy          WatchPolicy
+	labels               []string
+	disableHistory       bool
+	historyPath          string
+	outputParsers        []string
+	dryRun               bool
+	noProgress           bool
 }
 
 // Option ...
@@ -53,6 +70,13 @@ func ProfilePath(s string) Option {
 	}
 }
 
+// ProfileName selects a named profile within the profile file
+func ProfileName(s string) Option {
+	return func(o *Options) {
+		o.profileName = s
+	}
+}
+
 // Ratelimit ...
 func Ratelimit(d time.Duration) Option {
 	return func(o *Options) {
@@ -100,3 +124,136 @@ func ServerArch(s string) Option {
 		o.serverArch = s
 	}
 }
+
+// Output selects the output format. JSONLinesOutput disables colors and spinners
+func Output(f OutputFormat) Option {
+	return func(o *Options) {
+		o.outputFormat = f
+	}
+}
+
+// TrackCommands interleaves marker commands with the build commands so
+// that the output, duration and status of each command can be tracked.
+// The commands are always tracked if the job is recorded in the history
+// or notifiers are configured, since a failed build is detected that way.
+func TrackCommands(b bool) Option {
+	return func(o *Options) {
+		o.trackCommands = b
+	}
+}
+
+// JUnitReportPath writes a JUnit XML report of the build commands to path.
+// It enables command tracking.
+func JUnitReportPath(path string) Option {
+	return func(o *Options) {
+		o.junitReportPath = path
+		if path != "" {
+			o.trackCommands = true
+		}
+	}
+}
+
+// PrintCommandSummary prints a table with the duration and exit code of
+// each build command when the job finishes. It enables command tracking.
+func PrintCommandSummary(b bool) Option {
+	return func(o *Options) {
+		o.printCommandSummary = b
+		if b {
+			o.trackCommands = true
+		}
+	}
+}
+
+// RecordTranscript writes the full job output to disk. By default the
+// transcript is written under the output directory if one is set, and
+// under ~/.<app>/transcripts otherwise.
+func RecordTranscript(b bool) Option {
+	return func(o *Options) {
+		o.recordTranscript = b
+	}
+}
+
+// TranscriptPath records the transcript at path. The .log and .raw.log
+// extensions are appended for the stripped and raw variants.
+func TranscriptPath(path string) Option {
+	return func(o *Options) {
+		o.transcriptPath = path
+		o.recordTranscript = path != ""
+	}
+}
+
+// DeploymentName selects the deployment profile by name
+func DeploymentName(s string) Option {
+	return func(o *Options) {
+		o.deploymentName = s
+	}
+}
+
+// WriteMigratedSpec ...
+func WriteMigratedSpec(b bool) Option {
+	return func(o *Options) {
+		o.writeMigratedSpec = b
+	}
+}
+
+// OnInterrupt selects what happens to the submitted jobs when the
+// client is interrupted. See HandleSignals.
+func OnInterrupt(p InterruptPolicy) Option {
+	return func(o *Options) {
+		o.interruptPolicy = p
+	}
+}
+
+// OnProjectChange selects what happens to the job in flight when the
+// project changes in watch mode
+func OnProjectChange(p WatchPolicy) Option {
+	return func(o *Options) {
+		o.watchPolicy = p
+	}
+}
+
+// Labels tags the job in the local history
+func Labels(labels ...string) Option {
+	return func(o *Options) {
+		// the labels may be shared with the options of other jobs
+		o.labels = append(append([]string{}, o.labels...), labels...)
+	}
+}
+
+// HistoryPath records the jobs in the history at path
+func HistoryPath(path string) Option {
+	return func(o *Options) {
+		o.historyPath = path
+	}
+}
+
+// DisableHistory does not record the jobs in the local history
+func DisableHistory() Option {
+	return func(o *Options) {
+		o.disableHistory = true
+	}
+}
+
+// OutputParsers restricts the output parsers of the job to the named
+// ones. By default every registered and configured parser is used.
+func OutputParsers(names ...string) Option {
+	return func(o *Options) {
+		o.outputParsers = append(o.outputParsers, names...)
+	}
+}
+
+// DryRun validates the project and prints the job request, with the
+// secrets redacted, instead of uploading and submitting the project
+func DryRun(b bool) Option {
+	return func(o *Options) {
+		o.dryRun = b
+	}
+}
+
+// NoProgress disables the upload progress bar and the spinner shown
+// while the job is queued, for output that is not a terminal
+func NoProgress(b bool) Option {
+	return func(o *Options) {
+		o.noProgress = b
+	}
+}
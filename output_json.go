@@ -0,0 +1,213 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acarl005/stripansi"
+	"github.com/rai-project/model"
+	"github.com/rai-project/serializer/json"
+)
+
+// OutputFormat ...
+type OutputFormat string
+
+const (
+	// TextOutput is the default colored, human readable output
+	TextOutput OutputFormat = "text"
+	// JSONLinesOutput emits one JSON object per line
+	JSONLinesOutput OutputFormat = "jsonl"
+)
+
+// JSONLinesSchema identifies the schema of the JSON lines records. It
+// must be bumped whenever a field is removed or changes meaning.
+const JSONLinesSchema = "rai.client.jsonl/v1"
+
+// JSONLinesRecordType ...
+type JSONLinesRecordType string
+
+const (
+	// JSONLinesMessage is a message printed by the client
+	JSONLinesMessage JSONLinesRecordType = "message"
+	// JSONLinesProgress is an upload progress tick
+	JSONLinesProgress JSONLinesRecordType = "progress"
+	// JSONLinesLog is a line of the remote job output
+	JSONLinesLog JSONLinesRecordType = "log"
+	// JSONLinesResult is the final result of the job
+	JSONLinesResult JSONLinesRecordType = "result"
+)
+
+// jsonLinesProgressInterval is the minimum time between two progress records
+var jsonLinesProgressInterval = 500 * time.Millisecond
+
+// JSONLinesRecord is a single line of the JSON lines output
+type JSONLinesRecord struct {
+	Schema        string              `json:"schema"`
+	Type          JSONLinesRecordType `json:"type"`
+	Time          time.Time           `json:"time"`
+	JobID         string              `json:"job_id,omitempty"`
+	Stream        string              `json:"stream,omitempty"`
+	Message       string              `json:"message,omitempty"`
+	CreatedAt     *time.Time          `json:"created_at,omitempty"`
+	BytesUploaded int64               `json:"bytes_uploaded,omitempty"`
+	Done          bool                `json:"done,omitempty"`
+	Result        interface{}         `json:"result,omitempty"`
+}
+
+// jsonLinesEncoder serializes the records to the underlying writer
+type jsonLinesEncoder struct {
+	sync.Mutex
+	w     io.Writer
+	jobID string
+}
+
+func (e *jsonLinesEncoder) emit(rec JSONLinesRecord) {
+	e.Lock()
+	defer e.Unlock()
+	rec.Schema = JSONLinesSchema
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	if rec.JobID == "" {
+		rec.JobID = e.jobID
+	}
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		log.WithError(err).Debug("failed to marshal json lines record")
+		return
+	}
+	e.w.Write(append(buf, '\n'))
+}
+
+// jsonLinesWriter turns every line written to it into a message record.
+// ANSI escape sequences are removed.
+type jsonLinesWriter struct {
+	sync.Mutex
+	enc    *jsonLinesEncoder
+	stream string
+	buf    bytes.Buffer
+}
+
+func (w *jsonLinesWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// keep the incomplete line for the next write
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+		w.emit(line)
+	}
+	return len(p), nil
+}
+
+func (w *jsonLinesWriter) emit(line string) {
+	line = strings.TrimSpace(stripansi.Strip(line))
+	if line == "" {
+		return
+	}
+	w.enc.emit(JSONLinesRecord{
+		Type:    JSONLinesMessage,
+		Stream:  w.stream,
+		Message: line,
+	})
+}
+
+// Close flushes the incomplete line, if any
+func (w *jsonLinesWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	w.emit(w.buf.String())
+	w.buf.Reset()
+	return nil
+}
+
+// progressReader emits progress records while the upload is read
+type progressReader struct {
+	io.Reader
+	enc      *jsonLinesEncoder
+	read     int64
+	lastTick time.Time
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.read += int64(n)
+	if err == io.EOF {
+		r.enc.emit(JSONLinesRecord{
+			Type:          JSONLinesProgress,
+			BytesUploaded: r.read,
+			Done:          true,
+		})
+		return n, err
+	}
+	if time.Since(r.lastTick) >= jsonLinesProgressInterval {
+		r.lastTick = time.Now()
+		r.enc.emit(JSONLinesRecord{
+			Type:          JSONLinesProgress,
+			BytesUploaded: r.read,
+		})
+	}
+	return n, err
+}
+
+func (c *Client) isJSONLines() bool {
+	return c.jsonLines != nil
+}
+
+// emitLog emits a line of the remote job output
+func (c *Client) emitLog(resp model.JobResponse) {
+	body := strings.TrimSpace(stripansi.Strip(string(resp.Body)))
+	if body == "" {
+		return
+	}
+	createdAt := resp.CreatedAt
+	stream := transcriptStream(resp)
+	c.jsonLines.emit(JSONLinesRecord{
+		Type:      JSONLinesLog,
+		Stream:    stream,
+		Message:   body,
+		CreatedAt: &createdAt,
+	})
+}
+
+// JobResult is the final result of a job as reported in the JSON lines output
+type JobResult struct {
+	JobID        string          `json:"job_id"`
+	Status       JobStatus       `json:"status,omitempty"`
+	UploadKey    string          `json:"upload_key,omitempty"`
+	Queue        string          `json:"queue,omitempty"`
+	LintWarnings LintWarnings    `json:"lint_warnings,omitempty"`
+	Commands     []CommandResult `json:"commands,omitempty"`
+	Metrics      Metrics         `json:"metrics,omitempty"`
+	Body         interface{}     `json:"body,omitempty"`
+}
+
+// Result returns the result of the job
+func (c *Client) Result() JobResult {
+	return JobResult{
+		JobID:        c.ID.Hex(),
+		Status:       c.Status(),
+		UploadKey:    c.uploadKey,
+		Queue:        c.JobQueueName(),
+		LintWarnings: c.lintWarnings,
+		Commands:     c.CommandResults(),
+		Metrics:      c.Metrics(),
+		Body:         c.jobBody,
+	}
+}
+
+// emitResult emits the final result record
+func (c *Client) emitResult() {
+	c.jsonLines.emit(JSONLinesRecord{
+		Type:   JSONLinesResult,
+		Result: c.Result(),
+	})
+}
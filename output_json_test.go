@@ -0,0 +1,35 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rai-project/serializer/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLinesWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := &jsonLinesEncoder{w: buf, jobID: "5b0f1e3b"}
+	w := &jsonLinesWriter{enc: enc, stream: "stdout"}
+
+	fprintln(w, "\x1b[32m✱ Checking your authentication credentials.\x1b[0m")
+	fprint(w, "partial ")
+	fprint(w, "line")
+	require.NoError(t, w.Close())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var rec JSONLinesRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, JSONLinesSchema, rec.Schema)
+	assert.Equal(t, JSONLinesMessage, rec.Type)
+	assert.Equal(t, "5b0f1e3b", rec.JobID)
+	assert.Equal(t, "✱ Checking your authentication credentials.", rec.Message)
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &rec))
+	assert.Equal(t, "partial line", rec.Message)
+}
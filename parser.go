@@ -0,0 +1,328 @@
+package client
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acarl005/stripansi"
+	"github.com/pkg/errors"
+)
+
+// OutputParser extracts metrics from the output of a job. A parser is
+// created for each job and is fed every message of the job output, with
+// the color codes stripped, in the order they are received.
+type OutputParser interface {
+	Name() string
+	ParseLine(line string)
+	Metrics() Metrics
+}
+
+// bodyParser is implemented by the parsers that also build a structured
+// job body, such as the ECE408 ranking record
+type bodyParser interface {
+	Body() interface{}
+}
+
+// OutputParserFactory creates the parser of a job
+type OutputParserFactory func(j *Job) OutputParser
+
+var outputParsers = struct {
+	sync.Mutex
+	factories map[string]OutputParserFactory
+}{
+	factories: map[string]OutputParserFactory{},
+}
+
+// RegisterOutputParser makes the parser available to every job. A parser
+// registered with the name of an existing one replaces it.
+func RegisterOutputParser(name string, factory OutputParserFactory) {
+	outputParsers.Lock()
+	defer outputParsers.Unlock()
+	outputParsers.factories[name] = factory
+}
+
+// RegisteredOutputParsers returns the names of the registered parsers
+func RegisteredOutputParsers() []string {
+	outputParsers.Lock()
+	defer outputParsers.Unlock()
+	names := []string{}
+	for name := range outputParsers.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MetricAggregation decides how the values of a rule matching several
+// times are combined
+type MetricAggregation string
+
+const (
+	// AggregateLast keeps the last value
+	AggregateLast MetricAggregation = "last"
+	// AggregateFirst keeps the first value
+	AggregateFirst MetricAggregation = "first"
+	// AggregateSum adds the values
+	AggregateSum MetricAggregation = "sum"
+	// AggregateMin keeps the smallest value
+	AggregateMin MetricAggregation = "min"
+	// AggregateMax keeps the largest value
+	AggregateMax MetricAggregation = "max"
+	// AggregateAll keeps every value as <metric>.0, <metric>.1, ...
+	AggregateAll MetricAggregation = "all"
+)
+
+// MetricRule extracts a metric from the lines matching a regular
+// expression. The value is read from the capture group (the first one by
+// default) as a number or, failing that, as a duration or a clock time
+// such as 1:02.5 in seconds.
+type MetricRule struct {
+	Metric    string            `json:"metric" yaml:"metric" mapstructure:"metric"`
+	Regex     string            `json:"regex" yaml:"regex" mapstructure:"regex"`
+	Group     int               `json:"group" yaml:"group" mapstructure:"group"`
+	Unit      string            `json:"unit" yaml:"unit" mapstructure:"unit"`
+	Aggregate MetricAggregation `json:"aggregate" yaml:"aggregate" mapstructure:"aggregate"`
+}
+
+// OutputParserConfig declares a parser made of metric rules
+type OutputParserConfig struct {
+	Name  string       `json:"name" yaml:"name" mapstructure:"name"`
+	Rules []MetricRule `json:"rules" yaml:"rules" mapstructure:"rules"`
+}
+
+// The patterns of the ECE408 project output. They are shared by the
+// built-in ece408 parser and the ranking record of the ECE408 mode.
+const (
+	ece408CorrectnessPattern = `Correctness: ([-+]?[0-9]*\.?[0-9]+)\s+Model: (.*)`
+	ece408OpTimePattern      = `Op Time: ([-+]?[0-9]*\.?[0-9]+)`
+	ece408TimePattern        = `(?m)^([0-9]*\.?[0-9]+)user\s+([0-9]*\.?[0-9]+)system\s+([0-9]*:[0-9]*\.?[0-9]*)elapsed.+`
+)
+
+// ece408OutputParser extracts the correctness of each inference, the
+// time of each op and the elapsed time of each inference, as reported by
+// /usr/bin/time, from the output of the ECE408 project. It is registered
+// in every build mode.
+var ece408OutputParser = OutputParserConfig{
+	Name: "ece408",
+	Rules: []MetricRule{
+		{Metric: "correctness", Regex: ece408CorrectnessPattern, Aggregate: AggregateAll},
+		{Metric: "op_time", Regex: ece408OpTimePattern, Unit: "s", Aggregate: AggregateAll},
+		{Metric: "elapsed", Regex: ece408TimePattern, Group: 3, Unit: "s", Aggregate: AggregateAll},
+	},
+}
+
+func init() {
+	RegisterOutputParser(ece408OutputParser.Name, func(*Job) OutputParser {
+		p, err := NewRegexParser(ece408OutputParser)
+		if err != nil {
+			panic(err)
+		}
+		return p
+	})
+}
+
+type compiledRule struct {
+	MetricRule
+	re     *regexp.Regexp
+	values []float64
+}
+
+// RegexParser is an OutputParser built from metric rules
+type RegexParser struct {
+	name  string
+	rules []*compiledRule
+}
+
+// NewRegexParser compiles the rules of the parser
+func NewRegexParser(cfg OutputParserConfig) (*RegexParser, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("the output parser has no name")
+	}
+	p := &RegexParser{name: cfg.Name}
+	for _, rule := range cfg.Rules {
+		if rule.Metric == "" {
+			return nil, errors.Errorf("a rule of the output parser %v has no metric name", cfg.Name)
+		}
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid regex for the metric %v of the output parser %v", rule.Metric, cfg.Name)
+		}
+		if rule.Group == 0 {
+			rule.Group = 1
+		}
+		if rule.Group > re.NumSubexp() {
+			return nil, errors.Errorf("the regex for the metric %v of the output parser %v has no group %d",
+				rule.Metric, cfg.Name, rule.Group)
+		}
+		switch rule.Aggregate {
+		case "":
+			rule.Aggregate = AggregateLast
+		case AggregateLast, AggregateFirst, AggregateSum, AggregateMin, AggregateMax, AggregateAll:
+		default:
+			return nil, errors.Errorf("invalid aggregation %v for the metric %v of the output parser %v",
+				rule.Aggregate, rule.Metric, cfg.Name)
+		}
+		p.rules = append(p.rules, &compiledRule{MetricRule: rule, re: re})
+	}
+	return p, nil
+}
+
+// Name ...
+func (p *RegexParser) Name() string {
+	return p.name
+}
+
+func parseMetricValue(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, true
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d.Seconds(), true
+	}
+	// a clock time, [hours:]minutes:seconds
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	seconds := 0.0
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 {
+			return 0, false
+		}
+		seconds = seconds*60 + v
+	}
+	return seconds, true
+}
+
+// ParseLine records the values of the rules matching the line
+func (p *RegexParser) ParseLine(line string) {
+	for _, rule := range p.rules {
+		for _, matches := range rule.re.FindAllStringSubmatch(line, -1) {
+			v, ok := parseMetricValue(matches[rule.Group])
+			if !ok {
+				log.WithField("metric", rule.Metric).
+					WithField("value", matches[rule.Group]).
+					Debug("Unable to parse the metric value")
+				continue
+			}
+			rule.values = append(rule.values, v)
+		}
+	}
+}
+
+// Metrics returns the aggregated values of the rules that matched
+func (p *RegexParser) Metrics() Metrics {
+	metrics := Metrics{}
+	for _, rule := range p.rules {
+		if len(rule.values) == 0 {
+			continue
+		}
+		if rule.Aggregate == AggregateAll {
+			for ii, v := range rule.values {
+				metrics = append(metrics, Metric{Name: rule.Metric + "." + strconv.Itoa(ii), Value: v, Unit: rule.Unit})
+			}
+			continue
+		}
+		v := rule.values[len(rule.values)-1]
+		switch rule.Aggregate {
+		case AggregateFirst:
+			v = rule.values[0]
+		case AggregateSum:
+			v = 0
+			for _, x := range rule.values {
+				v += x
+			}
+		case AggregateMin:
+			for _, x := range rule.values {
+				v = math.Min(v, x)
+			}
+		case AggregateMax:
+			for _, x := range rule.values {
+				v = math.Max(v, x)
+			}
+		}
+		metrics = append(metrics, Metric{Name: rule.Metric, Value: v, Unit: rule.Unit})
+	}
+	return metrics
+}
+
+// newOutputParsers creates the parsers of the job: the registered ones
+// and the ones declared in the client config, restricted to the parsers
+// selected with the OutputParsers option
+func (c *Client) newOutputParsers() []OutputParser {
+	factories := map[string]OutputParserFactory{}
+	outputParsers.Lock()
+	for name, factory := range outputParsers.factories {
+		factories[name] = factory
+	}
+	outputParsers.Unlock()
+
+	for _, cfg := range Config.OutputParsers {
+		p, err := NewRegexParser(cfg)
+		if err != nil {
+			log.WithError(err).Error("ignoring the output parser")
+			continue
+		}
+		// the rules hold the values of the job, so each job needs its own copy
+		cfg := cfg
+		factories[p.Name()] = func(*Job) OutputParser {
+			p, _ := NewRegexParser(cfg)
+			return p
+		}
+	}
+
+	selected := c.options.outputParsers
+	if len(selected) == 0 {
+		for name := range factories {
+			selected = append(selected, name)
+		}
+		sort.Strings(selected)
+	}
+
+	parsers := []OutputParser{}
+	for _, name := range selected {
+		factory, ok := factories[name]
+		if !ok {
+			log.WithField("parser", name).Error("unknown output parser")
+			continue
+		}
+		parsers = append(parsers, factory(c.Job))
+	}
+	return parsers
+}
+
+// parseLine feeds a message of the job output to the output parsers
+func (c *Client) parseLine(s string) {
+	c.Job.mu.Lock()
+	defer c.Job.mu.Unlock()
+	if c.parsers == nil {
+		c.parsers = c.newOutputParsers()
+		for _, p := range c.parsers {
+			if bp, ok := p.(bodyParser); ok {
+				c.jobBody = bp.Body()
+			}
+		}
+	}
+	s = stripansi.Strip(s)
+	for _, p := range c.parsers {
+		p.ParseLine(s)
+	}
+}
+
+// parsedMetrics returns the metrics extracted by the output parsers
+func (c *Client) parsedMetrics() Metrics {
+	c.Job.mu.Lock()
+	defer c.Job.mu.Unlock()
+	metrics := Metrics{}
+	for _, p := range c.parsers {
+		metrics = append(metrics, p.Metrics()...)
+	}
+	return metrics
+}
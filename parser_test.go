@@ -0,0 +1,118 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexParser(t *testing.T) {
+	p, err := NewRegexParser(OutputParserConfig{
+		Name: "bench",
+		Rules: []MetricRule{
+			{Metric: "kernel_time", Regex: `Kernel time: (\S+)`, Unit: "s", Aggregate: AggregateSum},
+			{Metric: "accuracy", Regex: `Accuracy: ([0-9.]+)`},
+			{Metric: "layer", Regex: `Layer (\d+): (\S+)`, Group: 2, Unit: "s", Aggregate: AggregateAll},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "bench", p.Name())
+
+	for _, line := range []string{
+		"Kernel time: 1.5",
+		"Kernel time: 250ms",
+		"Accuracy: 0.5",
+		"Accuracy: 0.75",
+		"Layer 1: 0.1 Layer 2: 0.2",
+		"Kernel time: n/a",
+	} {
+		p.ParseLine(line)
+	}
+	assert.Equal(t, Metrics{
+		{Name: "kernel_time", Value: 1.75, Unit: "s"},
+		{Name: "accuracy", Value: 0.75},
+		{Name: "layer.0", Value: 0.1, Unit: "s"},
+		{Name: "layer.1", Value: 0.2, Unit: "s"},
+	}, p.Metrics())
+
+	_, err = NewRegexParser(OutputParserConfig{Name: "bad", Rules: []MetricRule{{Metric: "x", Regex: `(`}}})
+	assert.Error(t, err)
+	_, err = NewRegexParser(OutputParserConfig{Name: "bad", Rules: []MetricRule{{Metric: "x", Regex: `x`}}})
+	assert.Error(t, err, "the regex has no capture group")
+	_, err = NewRegexParser(OutputParserConfig{Name: "bad", Rules: []MetricRule{{Metric: "x", Regex: `(x)`, Aggregate: "median"}}})
+	assert.Error(t, err)
+}
+
+func TestClientOutputParsers(t *testing.T) {
+	defer func(parsers []OutputParserConfig) {
+		Config.OutputParsers = parsers
+	}(Config.OutputParsers)
+	Config.OutputParsers = []OutputParserConfig{
+		{Name: "test-throughput", Rules: []MetricRule{{Metric: "throughput", Regex: `(\d+) images/s`}}},
+	}
+
+	buf := new(bytes.Buffer)
+	c := &Client{
+		connection: newConnection(),
+		stdout:     nopWriterCloser{buf},
+		stderr:     nopWriterCloser{buf},
+	}
+	newJob(c)
+
+	c.parseLine("\x1b[32m1200 images/s\x1b[0m")
+	m, ok := c.Metrics().Get("throughput")
+	require.True(t, ok)
+	assert.Equal(t, 1200.0, m.Value)
+	assert.Contains(t, c.Result().Metrics, m, "the metrics are part of the job result")
+
+	c.options.outputParsers = []string{"unknown"}
+	c.parsers = nil
+	c.parseLine("1200 images/s")
+	_, ok = c.Metrics().Get("throughput")
+	assert.False(t, ok)
+
+	for _, name := range RegisteredOutputParsers() {
+		assert.False(t, strings.HasPrefix(name, "test-"), "configured parsers are not registered")
+	}
+}
+
+func TestEce408OutputParser(t *testing.T) {
+	assert.Contains(t, RegisteredOutputParsers(), "ece408")
+
+	p, err := NewRegexParser(ece408OutputParser)
+	require.NoError(t, err)
+	for _, line := range []string{
+		"Loading model... done\r\nNew Inference",
+		"Op Time: 0.1\nOp Time: 0.25",
+		"Correctness: 0.8451 Model: ece408",
+		"4.85user 2.97system 1:05.25elapsed 148%CPU (0avgtext+0avgdata 1319488maxresident)",
+	} {
+		p.ParseLine(line)
+	}
+	assert.Equal(t, Metrics{
+		{Name: "correctness.0", Value: 0.8451},
+		{Name: "op_time.0", Value: 0.1, Unit: "s"},
+		{Name: "op_time.1", Value: 0.25, Unit: "s"},
+		{Name: "elapsed.0", Value: 65.25, Unit: "s"},
+	}, p.Metrics())
+}
+
+func TestParseMetricValue(t *testing.T) {
+	for s, expected := range map[string]float64{
+		"1.5":     1.5,
+		"250ms":   0.25,
+		"0:05.25": 5.25,
+		"1:02:03": 3723,
+	} {
+		v, ok := parseMetricValue(s)
+		require.True(t, ok, s)
+		assert.InDelta(t, expected, v, 1e-9, s)
+	}
+	for _, s := range []string{"n/a", "1:x", ":"} {
+		_, ok := parseMetricValue(s)
+		assert.False(t, ok, s)
+	}
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/rai-project/auth"
+	"github.com/rai-project/auth/provider"
+	"github.com/rai-project/config"
+	"gopkg.in/yaml.v2"
+)
+
+// ProfileNameEnvironmentVariable returns the environment variable used to
+// select a named profile when the ProfileName option is not set (RAI_PROFILE)
+func ProfileNameEnvironmentVariable() string {
+	return strings.ToUpper(config.App.Name) + "_PROFILE"
+}
+
+// namedProfiles is the layout of a profile file containing several profiles
+//
+//	profiles:
+//	  student:
+//	    username: ...
+//	    access_key: ...
+//	    secret_key: ...
+//	  admin:
+//	    ...
+type namedProfiles struct {
+	Profiles map[string]yaml.MapSlice `yaml:"profiles"`
+}
+
+// profileName returns the selected profile name from the options or the environment
+func (c *Client) profileName() string {
+	if c.options.profileName != "" {
+		return c.options.profileName
+	}
+	return os.Getenv(ProfileNameEnvironmentVariable())
+}
+
+// ProfileNames returns the names of the profiles in the profile file
+func ProfileNames(profilePath string) ([]string, error) {
+	profiles, err := readNamedProfiles(profilePath)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name := range profiles.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func readNamedProfiles(profilePath string) (*namedProfiles, error) {
+	path, err := homedir.Expand(profilePath)
+	if err != nil {
+		path = profilePath
+	}
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read the profile file %v", profilePath)
+	}
+	var profiles namedProfiles
+	if err := yaml.Unmarshal(buf, &profiles); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse the profile file %v", profilePath)
+	}
+	return &profiles, nil
+}
+
+// namedProfile is a profile loaded from a section of a profile file. The
+// profile options point to the temporary file it was loaded from, so the
+// real profile file and the profile name are kept for error messages.
+type namedProfile struct {
+	auth.Profile
+	name string
+	path string
+}
+
+// describeProfile is used in error messages to describe where the
+// credentials of the profile come from
+func describeProfile(prof auth.Profile) string {
+	if p, ok := prof.(*namedProfile); ok {
+		return "the profile " + p.name + " in " + p.path
+	}
+	return prof.Options().ProfilePath
+}
+
+// newNamedProfile creates a profile from the named section of the
+// profile file. The auth provider only understands single profile
+// files, so the section is written to a private temporary file
+// which is removed once the profile has been loaded.
+func newNamedProfile(profilePath, name string) (auth.Profile, error) {
+	profiles, err := readNamedProfiles(profilePath)
+	if err != nil {
+		return nil, err
+	}
+	section, ok := profiles.Profiles[name]
+	if !ok {
+		names, _ := ProfileNames(profilePath)
+		return nil, errors.Errorf("the profile %v was not found in %v. Available profiles are %v", name, profilePath, names)
+	}
+
+	buf, err := yaml.Marshal(yaml.MapSlice{
+		{Key: "profile", Value: section},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to serialize the profile %v", name)
+	}
+
+	// the file holds the secret key: it is only readable by the user and
+	// is removed whether or not the profile could be loaded
+	f, err := ioutil.TempFile("", config.App.Name+"_profile_")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create a temporary profile file")
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "unable to restrict the permissions of the temporary profile file")
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "unable to write the temporary profile file")
+	}
+	if err := f.Close(); err != nil {
+		return nil, errors.Wrap(err, "unable to write the temporary profile file")
+	}
+
+	prof, err := provider.New(auth.ProfilePath(f.Name()))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to load the profile %v in %v", name, profilePath)
+	}
+	return &namedProfile{Profile: prof, name: name, path: profilePath}, nil
+}
+
+// newProfile creates the profile selected by the client options. All the
+// code paths that need the user's credentials must go through this
+// function (or use c.profile) so that the same identity is used.
+func (c *Client) newProfile() (auth.Profile, error) {
+	name := c.profileName()
+	if name == "" {
+		return provider.New(auth.ProfilePath(c.options.profilePath))
+	}
+	log.WithField("profile", name).Debug("using named profile")
+	return newNamedProfile(c.options.profilePath, name)
+}
+
+// profileDescription is used in error messages to describe the selected profile
+func (c *Client) profileDescription() string {
+	if name := c.profileName(); name != "" {
+		return "the profile " + name + " in " + c.options.profilePath
+	}
+	return c.options.profilePath
+}
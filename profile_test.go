@@ -0,0 +1,88 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNamedProfiles = `profiles:
+  student:
+    username: student
+    access_key: student-access-key
+    secret_key: student-secret-key
+  admin:
+    username: admin
+    access_key: admin-access-key
+    secret_key: admin-secret-key
+`
+
+func writeTestProfiles(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "profile.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(testNamedProfiles), 0600))
+	return path
+}
+
+func TestReadNamedProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeTestProfiles(t, dir)
+
+	profiles, err := readNamedProfiles(path)
+	require.NoError(t, err)
+	require.Contains(t, profiles.Profiles, "admin")
+	assert.Equal(t, "admin-access-key", mapSliceGet(profiles.Profiles["admin"], "access_key"))
+
+	names, err := ProfileNames(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "student"}, names)
+
+	_, err = readNamedProfiles(filepath.Join(dir, "missing.yml"))
+	assert.Error(t, err)
+}
+
+func TestProfileName(t *testing.T) {
+	env := ProfileNameEnvironmentVariable()
+	defer os.Setenv(env, os.Getenv(env))
+
+	c := &Client{}
+	os.Setenv(env, "")
+	assert.Equal(t, "", c.profileName())
+
+	os.Setenv(env, "admin")
+	assert.Equal(t, "admin", c.profileName())
+
+	ProfileName("student")(&c.options)
+	assert.Equal(t, "student", c.profileName(), "the option wins over the environment")
+}
+
+func TestNewNamedProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeTestProfiles(t, dir)
+
+	// the temporary profile files are created in TMPDIR
+	tmp := filepath.Join(dir, "tmp")
+	require.NoError(t, os.Mkdir(tmp, 0700))
+	defer os.Setenv("TMPDIR", os.Getenv("TMPDIR"))
+	os.Setenv("TMPDIR", tmp)
+
+	_, err = newNamedProfile(path, "ta")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the profile ta was not found")
+	assert.Contains(t, err.Error(), "[admin student]")
+
+	prof, err := newNamedProfile(path, "student")
+	if err == nil {
+		assert.Equal(t, "the profile student in "+path, describeProfile(prof), "errors refer to the real profile file")
+	}
+	files, err := ioutil.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, files, "the temporary profile file is removed")
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/pkg/errors"
-	"github.com/rai-project/auth/provider"
 	"github.com/rai-project/config"
 	"github.com/rai-project/database/mongodb"
 	"github.com/spf13/cast"
@@ -55,12 +54,20 @@ func (c *Client) RecordJob() error {
 		}
 	}
 
-	prof, err := provider.New()
+	prof := c.profile
+	if prof == nil {
+		var err error
+		prof, err = c.newProfile()
+		if err != nil {
+			return err
+		}
+	}
 	user := prof.Info()
 	body.Username = user.Username
 	body.UserAccessKey = user.AccessKey
 
-	body.Teamname, err = FindTeamName(body.Username)
+	var err error
+	body.Teamname, err = c.findTeamName(body.Username)
 	if err != nil && body.IsSubmission {
 		color.Red("no team name found.\n")
 		body.Teamname = user.Team.Name
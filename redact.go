@@ -0,0 +1,190 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rai-project/aws"
+	"github.com/sirupsen/logrus"
+)
+
+// RedactedPlaceholder replaces secrets in the client output
+const RedactedPlaceholder = "********"
+
+// secrets shorter than this are not redacted since
+// masking them would mangle unrelated output
+const minRedactedSecretLength = 4
+
+// Redactor masks known secrets in strings
+type Redactor struct {
+	sync.RWMutex
+	secrets []string
+}
+
+// Secrets is the redactor used by the client for logs, streamed output and errors.
+// It knows all the secrets loaded from the profile and the docker credentials.
+var Secrets = &Redactor{}
+
+// Add registers secrets to be redacted
+func (r *Redactor) Add(secrets ...string) {
+	r.Lock()
+	defer r.Unlock()
+	for _, s := range secrets {
+		if len(s) < minRedactedSecretLength {
+			continue
+		}
+		known := false
+		for _, e := range r.secrets {
+			if e == s {
+				known = true
+				break
+			}
+		}
+		if !known {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	// replace the longest secrets first so that a secret
+	// which contains another one is fully masked
+	sort.Slice(r.secrets, func(ii, jj int) bool {
+		return len(r.secrets[ii]) > len(r.secrets[jj])
+	})
+}
+
+// Redact replaces all the known secrets in s
+func (r *Redactor) Redact(s string) string {
+	r.RLock()
+	defer r.RUnlock()
+	for _, secret := range r.secrets {
+		if strings.Contains(s, secret) {
+			s = strings.Replace(s, secret, RedactedPlaceholder, -1)
+		}
+	}
+	return s
+}
+
+// redactedError masks the secrets in the error message
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return Secrets.Redact(e.err.Error())
+}
+
+// Cause ...
+func (e *redactedError) Cause() error {
+	return e.err
+}
+
+// redactError masks the secrets in the error message. The error is
+// returned as is if it does not contain any secret, so that it can
+// still be compared to sentinel errors such as context.Canceled.
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if Secrets.Redact(err.Error()) == err.Error() {
+		return err
+	}
+	if _, ok := err.(*ValidationError); ok {
+		return &ValidationError{Message: Secrets.Redact(err.Error())}
+	}
+	return &redactedError{err: err}
+}
+
+// redactWriter masks the secrets written to the underlying writer. The
+// output is buffered up to the end of the line, so that a secret split
+// across two writes is masked as well.
+type redactWriter struct {
+	mu  sync.Mutex
+	buf []byte
+	io.WriteCloser
+}
+
+func newRedactWriter(w io.WriteCloser) io.WriteCloser {
+	if w == nil {
+		return nil
+	}
+	return &redactWriter{WriteCloser: w}
+}
+
+func (w *redactWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	// carriage returns end the lines of the progress bars and spinners
+	end := bytes.LastIndexAny(w.buf, "\r\n")
+	if end < 0 {
+		return len(p), nil
+	}
+	line := w.buf[:end+1]
+	w.buf = append([]byte{}, w.buf[end+1:]...)
+	if _, err := io.WriteString(w.WriteCloser, Secrets.Redact(string(line))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes the incomplete line, if any, and closes the underlying writer
+func (w *redactWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) != 0 {
+		io.WriteString(w.WriteCloser, Secrets.Redact(string(w.buf)))
+		w.buf = nil
+	}
+	return w.WriteCloser.Close()
+}
+
+// redactHook masks the secrets in every log entry
+type redactHook struct{}
+
+// Levels ...
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire ...
+func (redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = Secrets.Redact(entry.Message)
+	for k, v := range entry.Data {
+		switch v := v.(type) {
+		case string:
+			if r := Secrets.Redact(v); r != v {
+				entry.Data[k] = r
+			}
+		case error:
+			if r := Secrets.Redact(v.Error()); r != v.Error() {
+				entry.Data[k] = redactError(v)
+			}
+		}
+	}
+	return nil
+}
+
+// registerSecrets records the secrets loaded from the profile, the
+// aws configuration and the docker credentials so that they are
+// redacted from the client output
+func (c *Client) registerSecrets() {
+	Secrets.Add(aws.Config.AccessKey, aws.Config.SecretKey)
+	for _, creds := range Config.Registries {
+		Secrets.Add(creds.Password)
+	}
+	for _, n := range Config.Notifiers {
+		Secrets.Add(n.Password)
+	}
+	if c.profile != nil {
+		info := c.profile.Info()
+		Secrets.Add(info.AccessKey, info.SecretKey)
+		if info.DockerHub != nil {
+			Secrets.Add(info.DockerHub.Password)
+		}
+	}
+	if buildImage := c.buildSpec.Commands.BuildImage; buildImage != nil && buildImage.Push != nil {
+		Secrets.Add(buildImage.Push.Credentials.Password)
+	}
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor(t *testing.T) {
+	r := &Redactor{}
+	r.Add("abc", "secretkey", "secretkey-long")
+	assert.Equal(t, "abc "+RedactedPlaceholder+" "+RedactedPlaceholder, r.Redact("abc secretkey secretkey-long"))
+}
+
+func TestRedactWriterAndError(t *testing.T) {
+	Secrets.Add("sup3r-s3cret")
+
+	buf := new(bytes.Buffer)
+	w := newRedactWriter(nopWriterCloser{buf})
+	n, err := w.Write([]byte("password=sup3r-s3cret\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, len("password=sup3r-s3cret\n"), n)
+	assert.Equal(t, "password="+RedactedPlaceholder+"\n", buf.String())
+
+	cause := errors.New("login failed for sup3r-s3cret")
+	err = redactError(cause)
+	assert.Equal(t, "login failed for "+RedactedPlaceholder, err.Error())
+	assert.Equal(t, cause, errors.Cause(err))
+	assert.Nil(t, redactError(nil))
+}
+
+func TestRedactWriterSplitSecret(t *testing.T) {
+	Secrets.Add("split-s3cret")
+
+	buf := new(bytes.Buffer)
+	w := newRedactWriter(nopWriterCloser{buf})
+	fprint(w, "token=split-")
+	assert.Empty(t, buf.String(), "incomplete lines are buffered")
+	fprint(w, "s3cret\nnext=split")
+	assert.Equal(t, "token="+RedactedPlaceholder+"\n", buf.String())
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "token="+RedactedPlaceholder+"\nnext=split", buf.String())
+}
+
+func TestRedactErrorKeepsSentinels(t *testing.T) {
+	assert.Equal(t, context.Canceled, redactError(context.Canceled))
+}
+
+func TestDryRunIsRedacted(t *testing.T) {
+	Secrets.Add("dryrun-s3cret")
+
+	buf := new(bytes.Buffer)
+	c := &Client{
+		connection: newConnection(),
+		options:    Options{directory: "/projects/a", dryRun: true},
+		stdout:     nopWriterCloser{buf},
+		stderr:     nopWriterCloser{buf},
+	}
+	newJob(c)
+	c.buildSpec = lintSpec(t, `
+rai:
+  version: 0.2
+  image: webgpu/rai:amd64-cpu
+commands:
+  build:
+    - docker login -p dryrun-s3cret
+`)
+	require.NoError(t, c.printDryRun())
+	assert.Contains(t, buf.String(), "docker login -p "+RedactedPlaceholder)
+	assert.NotContains(t, buf.String(), "dryrun-s3cret")
+	assert.NoError(t, c.Job.Wait(), "a dry run does not wait for the server")
+}
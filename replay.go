@@ -0,0 +1,200 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/acarl005/stripansi"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/rai-project/model"
+	"github.com/rai-project/serializer/json"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/yaml.v2"
+)
+
+var transcriptLineRe = regexp.MustCompile(`^(\S+) (stdout|stderr) \| (.*)$`)
+
+// replayMessage is a message of the job output read back from a transcript
+type replayMessage struct {
+	at     string
+	stream string
+	lines  []string
+}
+
+// transcriptReplayer reads a transcript written by the client (either the
+// plain .log/.raw.log files or the JSON lines output) or a plain log
+// copied from the terminal
+type transcriptReplayer struct {
+	jobID    string
+	spec     []string
+	inSpec   bool
+	messages []replayMessage
+}
+
+func (r *transcriptReplayer) readHeader(line string) {
+	line = strings.TrimPrefix(strings.TrimPrefix(line, "#"), " ")
+	if r.inSpec {
+		if strings.HasPrefix(line, "  ") {
+			r.spec = append(r.spec, strings.TrimPrefix(line, "  "))
+			return
+		}
+		r.inSpec = false
+	}
+	switch {
+	case strings.HasPrefix(line, "job_id: "):
+		r.jobID = strings.TrimSpace(strings.TrimPrefix(line, "job_id: "))
+	case line == "build_specification:":
+		r.inSpec = true
+	}
+}
+
+// add appends the line to the current message if it has the same server
+// timestamp and stream, since the transcript splits messages into lines
+func (r *transcriptReplayer) add(at, stream, line string) {
+	if n := len(r.messages); n > 0 && at != "" {
+		last := &r.messages[n-1]
+		if last.at == at && last.stream == stream {
+			last.lines = append(last.lines, line)
+			return
+		}
+	}
+	r.messages = append(r.messages, replayMessage{
+		at:     at,
+		stream: stream,
+		lines:  []string{line},
+	})
+}
+
+// scanTranscriptLines splits on the line feeds only, unlike
+// bufio.ScanLines, so that the carriage returns of the job output
+// recorded in the raw transcript are kept
+func scanTranscriptLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if ii := bytes.IndexByte(data, '\n'); ii >= 0 {
+		return ii + 1, data[:ii], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func (r *transcriptReplayer) read(rd io.Reader) error {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	scanner.Split(scanTranscriptLines)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "#"):
+			r.readHeader(strings.TrimRight(line, "\r"))
+		case strings.HasPrefix(line, "{"):
+			var rec JSONLinesRecord
+			if err := json.Unmarshal([]byte(strings.TrimRight(line, "\r")), &rec); err != nil || rec.Type != JSONLinesLog {
+				continue
+			}
+			if r.jobID == "" {
+				r.jobID = rec.JobID
+			}
+			at := ""
+			if rec.CreatedAt != nil {
+				at = rec.CreatedAt.Format(transcriptTimeFormat)
+			}
+			r.messages = append(r.messages, replayMessage{
+				at:     at,
+				stream: rec.Stream,
+				lines:  []string{rec.Message},
+			})
+		default:
+			if m := transcriptLineRe.FindStringSubmatch(line); len(m) == 4 {
+				r.add(m[1], m[2], m[3])
+				continue
+			}
+			r.add("", "stdout", line)
+		}
+	}
+	return scanner.Err()
+}
+
+func (m replayMessage) response() model.JobResponse {
+	resp := model.JobResponse{
+		Kind: model.StdoutResponse,
+		Body: []byte(strings.Join(m.lines, "\n")),
+	}
+	if m.stream == "stderr" {
+		resp.Kind = model.StderrResponse
+	}
+	if at, err := time.Parse(transcriptTimeFormat, m.at); err == nil {
+		resp.CreatedAt = at
+	}
+	return resp
+}
+
+// ReplayTranscript feeds a saved transcript through the output parsers to
+// rebuild the job result without rerunning the job. The transcript is
+// replayed into a new job whose ID and build specification are restored
+// from the transcript header when present. If record is true the rebuilt
+// result is recorded using RecordJob.
+func (c *Client) ReplayTranscript(rd io.Reader, record bool) (JobResult, error) {
+	replayer := &transcriptReplayer{}
+	if err := replayer.read(rd); err != nil {
+		return JobResult{}, errors.Wrap(err, "unable to read the transcript")
+	}
+	if replayer.jobID != "" && !bson.IsObjectIdHex(replayer.jobID) {
+		return JobResult{}, errors.Errorf("invalid job id %v in the transcript", replayer.jobID)
+	}
+
+	clnt := c.newJobClient()
+	// the replayed job is not submitted, so it is not one of the jobs of
+	// the connection, which are keyed by ID
+	clnt.connection.mu.Lock()
+	delete(clnt.connection.jobs, clnt.ID)
+	clnt.connection.mu.Unlock()
+	clnt.logs.close()
+	clnt.closeFinished()
+
+	if replayer.jobID != "" {
+		clnt.ID = bson.ObjectIdHex(replayer.jobID)
+	}
+	if len(replayer.spec) != 0 {
+		if err := yaml.Unmarshal([]byte(strings.Join(replayer.spec, "\n")), &clnt.buildSpec); err != nil {
+			return JobResult{}, errors.Wrap(err, "unable to parse the build specification in the transcript")
+		}
+	}
+
+	for _, msg := range replayer.messages {
+		resp := msg.response()
+		body := strings.TrimSpace(string(resp.Body))
+		if strings.HasPrefix(stripansi.Strip(body), commandMarker()) {
+			continue
+		}
+		clnt.parseLine(body)
+	}
+
+	fprintln(clnt.options.stdout, color.GreenString("✱ Replayed %d messages of job %s.", len(replayer.messages), clnt.ID.Hex()))
+
+	if record {
+		if err := clnt.RecordJob(); err != nil {
+			return JobResult{}, err
+		}
+	}
+	return clnt.Result(), nil
+}
+
+// ReplayTranscriptFile replays the transcript at path
+func (c *Client) ReplayTranscriptFile(path string, record bool) (JobResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return JobResult{}, errors.Wrapf(err, "unable to open %v", path)
+	}
+	defer f.Close()
+	return c.ReplayTranscript(f, record)
+}
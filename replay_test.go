@@ -0,0 +1,46 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptReplayer(t *testing.T) {
+	transcript := `# job_id: 5b0f1e3b9c1d2a0001a2b3c4
+# queue: rai_amd64
+# build_specification:
+#   rai:
+#     version: "0.2"
+2018-06-01T10:00:00.1Z stdout | Loading model... done
+2018-06-01T10:00:00.1Z stdout | New Inference
+2018-06-01T10:00:01.5Z stderr | Op Time: 0.1
+{"schema":"rai.client.jsonl/v1","type":"log","stream":"stdout","message":"Correctness: 0.8 Model: ece408","created_at":"2018-06-01T10:00:02Z"}
+{"schema":"rai.client.jsonl/v1","type":"message","message":"✱ Checking your authentication credentials."}
+a plain line
+`
+	r := &transcriptReplayer{}
+	require.NoError(t, r.read(strings.NewReader(transcript)))
+	assert.Equal(t, "5b0f1e3b9c1d2a0001a2b3c4", r.jobID)
+	assert.Equal(t, []string{"rai:", `  version: "0.2"`}, r.spec)
+	require.Len(t, r.messages, 4)
+	assert.Equal(t, "Loading model... done\nNew Inference", string(r.messages[0].response().Body))
+	assert.Equal(t, "stderr", r.messages[1].stream)
+	assert.Equal(t, "Correctness: 0.8 Model: ece408", string(r.messages[2].response().Body))
+	assert.False(t, r.messages[2].response().CreatedAt.IsZero())
+	assert.Equal(t, "a plain line", r.messages[3].lines[0])
+}
+
+func TestReplayTranscriptNewJob(t *testing.T) {
+	c := &Client{connection: newConnection()}
+	newJob(c)
+	id := c.ID
+
+	res, err := c.ReplayTranscript(strings.NewReader("# job_id: 5b0f1e3b9c1d2a0001a2b3c4\na plain line\n"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "5b0f1e3b9c1d2a0001a2b3c4", res.JobID)
+	assert.Equal(t, id, c.ID, "the job of the client is not modified")
+	assert.Len(t, c.connection.jobs, 1, "the replayed job is not tracked by the connection")
+}
@@ -1,8 +1,109 @@
 package client
 
-import "github.com/rai-project/config"
+import (
+	"path"
+	"strconv"
+	"strings"
 
+	"github.com/rai-project/config"
+)
+
+// QueueRoute maps a (cpu arch, gpu arch, gpu count, role, submission kind)
+// pattern to a queue name. Each field is a glob pattern (see path.Match)
+// and an empty field matches anything. For example
+//
+//	client:
+//	  queue_routes:
+//	    - gpu_architecture: volta
+//	      queue: rai_amd64_volta
+//	    - role: ece408*
+//	      submission_kind: final
+//	      queue: rai_amd64_ece408
+type QueueRoute struct {
+	CPUArchitecture string `json:"cpu_architecture" yaml:"cpu_architecture" mapstructure:"cpu_architecture"`
+	GPUArchitecture string `json:"gpu_architecture" yaml:"gpu_architecture" mapstructure:"gpu_architecture"`
+	GPUCount        string `json:"gpu_count" yaml:"gpu_count" mapstructure:"gpu_count"`
+	Role            string `json:"role" yaml:"role" mapstructure:"role"`
+	SubmissionKind  string `json:"submission_kind" yaml:"submission_kind" mapstructure:"submission_kind"`
+	Queue           string `json:"queue" yaml:"queue" mapstructure:"queue"`
+}
+
+// QueueRouteInput are the properties of a job used to select a queue
+type QueueRouteInput struct {
+	CPUArchitecture string
+	GPUArchitecture string
+	GPUCount        int
+	Role            string
+	SubmissionKind  string
+}
+
+func matchRoutePattern(pattern, s string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	ok, err := path.Match(strings.ToLower(pattern), strings.ToLower(s))
+	return err == nil && ok
+}
+
+// Matches returns true if all the route's patterns match the input
+func (r QueueRoute) Matches(in QueueRouteInput) bool {
+	return matchRoutePattern(r.CPUArchitecture, in.CPUArchitecture) &&
+		matchRoutePattern(r.GPUArchitecture, in.GPUArchitecture) &&
+		matchRoutePattern(r.GPUCount, strconv.Itoa(in.GPUCount)) &&
+		matchRoutePattern(r.Role, in.Role) &&
+		matchRoutePattern(r.SubmissionKind, in.SubmissionKind)
+}
+
+// RouteQueue returns the first route that matches the input. The
+// routes are evaluated in order.
+func RouteQueue(routes []QueueRoute, in QueueRouteInput) (QueueRoute, bool) {
+	for _, r := range routes {
+		if r.Queue == "" {
+			continue
+		}
+		if r.Matches(in) {
+			return r, true
+		}
+	}
+	return QueueRoute{}, false
+}
+
+func (c *Client) queueRouteInput() QueueRouteInput {
+	in := QueueRouteInput{
+		CPUArchitecture: c.buildSpec.Resources.CPU.Architecture,
+		SubmissionKind:  c.submissionKindName(),
+	}
+	if gpu := c.buildSpec.Resources.GPU; gpu != nil {
+		in.GPUArchitecture = gpu.Architecture
+		in.GPUCount = gpu.Count
+	}
+	if c.profile != nil {
+		if role, err := c.profile.GetRole(); err == nil {
+			in.Role = string(role)
+		}
+	}
+	return in
+}
+
+// selectBuildQueue infers the queue from the build file. A matching route
+// of the routing table in the client config wins over the configured queue.
+// Otherwise the queue falls back to <app>_<cpu arch>, but only if no queue
+// is configured, so that a configured client.job_queue_name is kept.
 func (c *Client) selectBuildQueue() {
-	c.buildFileJobQueueName = config.App.Name + "_" + c.buildSpec.Resources.CPU.Architecture
+	in := c.queueRouteInput()
+	if route, ok := RouteQueue(Config.QueueRoutes, in); ok {
+		c.buildFileJobQueueName = route.Queue
+		log.WithField("route", route).
+			WithField("input", in).
+			Debug("selected queue ", route.Queue, " from the routing table. May be overridden by client.Options")
+		return
+	}
+	if in.CPUArchitecture == "" {
+		return
+	}
+	if c.configJobQueueName != "" && c.configJobQueueName != defaultJobQueueName() {
+		return
+	}
+	c.buildFileJobQueueName = config.App.Name + "_" + in.CPUArchitecture
 	log.Debug("inferring queue ", c.buildFileJobQueueName, " from build file. May be overridden by client.Options")
 }
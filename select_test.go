@@ -0,0 +1,47 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/rai-project/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteQueue(t *testing.T) {
+	routes := []QueueRoute{
+		{GPUArchitecture: "volta", GPUCount: "[2-8]", Queue: "rai_amd64_volta_multi"},
+		{GPUArchitecture: "volta", Queue: "rai_amd64_volta"},
+		{Role: "ece408*", SubmissionKind: "final", Queue: "rai_amd64_ece408"},
+		{CPUArchitecture: "ppc64le", Queue: "rai_ppc64le"},
+	}
+
+	route, ok := RouteQueue(routes, QueueRouteInput{CPUArchitecture: "amd64", GPUArchitecture: "Volta", GPUCount: 1})
+	assert.True(t, ok)
+	assert.Equal(t, "rai_amd64_volta", route.Queue)
+
+	route, ok = RouteQueue(routes, QueueRouteInput{CPUArchitecture: "amd64", GPUArchitecture: "volta", GPUCount: 4})
+	assert.True(t, ok)
+	assert.Equal(t, "rai_amd64_volta_multi", route.Queue)
+
+	route, ok = RouteQueue(routes, QueueRouteInput{CPUArchitecture: "amd64", Role: "ece408_student", SubmissionKind: "final"})
+	assert.True(t, ok)
+	assert.Equal(t, "rai_amd64_ece408", route.Queue)
+
+	_, ok = RouteQueue(routes, QueueRouteInput{CPUArchitecture: "amd64", GPUArchitecture: "pascal", GPUCount: 1})
+	assert.False(t, ok)
+}
+
+func TestSelectBuildQueue(t *testing.T) {
+	defer func(routes []QueueRoute) { Config.QueueRoutes = routes }(Config.QueueRoutes)
+	Config.QueueRoutes = []QueueRoute{{GPUArchitecture: "volta", Queue: "rai_amd64_volta"}}
+
+	c := &Client{connection: newConnection(), configJobQueueName: "rai_custom"}
+	newJob(c)
+	c.buildSpec.Resources.CPU.Architecture = "ppc64le"
+	c.selectBuildQueue()
+	assert.Equal(t, "rai_custom", c.JobQueueName(), "the configured queue wins over the cpu architecture")
+
+	c.configJobQueueName = defaultJobQueueName()
+	c.selectBuildQueue()
+	assert.Equal(t, config.App.Name+"_ppc64le", c.JobQueueName())
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+// InterruptPolicy decides what happens to the submitted jobs when the
+// client is interrupted
+type InterruptPolicy string
+
+const (
+	// InterruptLeaveRunning leaves the jobs running on the server and
+	// prints their IDs. A second interrupt within the grace period
+	// cancels them instead.
+	InterruptLeaveRunning InterruptPolicy = "leave"
+	// InterruptCancelJob cancels the jobs on the server
+	InterruptCancelJob InterruptPolicy = "cancel"
+)
+
+// ErrInterrupted is returned by Wait when the client is interrupted
+var ErrInterrupted = errors.New("the client was interrupted")
+
+func (c *Client) interruptPolicy() InterruptPolicy {
+	if c.options.interruptPolicy != "" {
+		return c.options.interruptPolicy
+	}
+	if Config.InterruptPolicy != "" {
+		return InterruptPolicy(Config.InterruptPolicy)
+	}
+	return InterruptLeaveRunning
+}
+
+// ctxReader fails the reads once the context is done. It is used to
+// abort the upload of the project directory.
+type ctxReader struct {
+	ctx context.Context
+	io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.Reader.Read(p)
+}
+
+// HandleSignals traps SIGINT and SIGTERM until stop is called. On the
+// first signal the current phase (upload, wait, ...) is aborted, the
+// submitted jobs are cancelled on the server or left running depending
+// on the interrupt policy, and the client is disconnected. Wait then
+// returns ErrInterrupted. Jobs submitted after HandleSignals is called
+// are handled as well.
+func (c *Client) HandleSignals() (stop func()) {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	stopHandling := c.handleSignals(sigs)
+	return func() {
+		signal.Stop(sigs)
+		stopHandling()
+	}
+}
+
+// handleSignals interrupts the client on the first signal received on sigs
+func (c *Client) handleSignals(sigs chan os.Signal) (stop func()) {
+	ctx, cancel := context.WithCancel(c.options.ctx)
+	c.options.ctx = ctx
+
+	interrupted := make(chan struct{})
+	c.connection.mu.Lock()
+	c.interrupted = interrupted
+	c.connection.mu.Unlock()
+
+	quit := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-sigs:
+			cancel()
+			c.interrupt(sig, sigs)
+			close(interrupted)
+		case <-quit:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(quit)
+			cancel()
+		})
+	}
+}
+
+// publishedJobs returns the jobs of the client that are in the queue or running
+func (c *Client) publishedJobs() []*Job {
+	c.connection.mu.Lock()
+	defer c.connection.mu.Unlock()
+	jobs := []*Job{}
+	for _, job := range c.jobs {
+		if s := job.Status(); s == JobQueued || s == JobRunning {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+func (c *Client) interrupt(sig os.Signal, sigs <-chan os.Signal) {
+	if c.spinner != nil {
+		c.spinner.Stop()
+	}
+	fprintln(c.options.stdout, color.YellowString("✱ Received "+sig.String()+". Stopping the client."))
+
+	jobs := c.publishedJobs()
+	cancelJobs := c.interruptPolicy() == InterruptCancelJob
+	if !cancelJobs && len(jobs) != 0 && Config.InterruptGracePeriod > 0 {
+		fprintln(c.options.stdout, color.YellowString("✱ Interrupt again within "+
+			Config.InterruptGracePeriod.String()+" to cancel the job on the server."))
+		grace := time.NewTimer(Config.InterruptGracePeriod)
+		select {
+		case <-sigs:
+			cancelJobs = true
+		case <-grace.C:
+		}
+		grace.Stop()
+	}
+
+	for _, job := range jobs {
+		if cancelJobs {
+			ctx, cancel := context.WithTimeout(context.Background(), Config.CancelTimeout)
+			// another interrupt stops waiting for the server
+			go func() {
+				select {
+				case <-sigs:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			_, err := job.Cancel(ctx)
+			cancel()
+			if err == nil {
+				continue
+			}
+			log.WithError(err).Error("failed to cancel the job")
+		}
+		fprintln(c.options.stdout, color.YellowString("✱ Job "+job.ID.Hex()+
+			" is still running on the server. Use its ID to find the results later."))
+	}
+
+	if err := c.Disconnect(); err != nil {
+		log.WithError(err).Debug("failed to disconnect")
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCtxReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := ctxReader{ctx: ctx, Reader: strings.NewReader("project")}
+
+	buf := make([]byte, 3)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "pro", string(buf[:n]))
+
+	cancel()
+	_, err = r.Read(buf)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestInterruptPolicy(t *testing.T) {
+	clnt := &Client{}
+	assert.Equal(t, InterruptLeaveRunning, clnt.interruptPolicy())
+
+	OnInterrupt(InterruptCancelJob)(&clnt.options)
+	assert.Equal(t, InterruptCancelJob, clnt.interruptPolicy())
+}
+
+func TestHandleSignals(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signals")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	clnt := &Client{
+		connection: newConnection(),
+		options:    Options{ctx: context.Background()},
+	}
+	newJob(clnt)
+	HistoryPath(filepath.Join(dir, "history.jsonl"))(&clnt.options)
+
+	sigs := make(chan os.Signal, 2)
+	stop := clnt.handleSignals(sigs)
+	defer stop()
+
+	sigs <- os.Interrupt
+
+	errc := make(chan error, 1)
+	go func() { errc <- clnt.Wait() }()
+	select {
+	case err := <-errc:
+		assert.Equal(t, ErrInterrupted, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return after the interrupt")
+	}
+	assert.Error(t, clnt.options.ctx.Err())
+	assert.Equal(t, JobInterrupted, clnt.Status())
+
+	e, err := OpenHistory(filepath.Join(dir, "history.jsonl")).Show(clnt.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, JobInterrupted, e.Status)
+}
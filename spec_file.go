@@ -9,9 +9,14 @@ import (
 )
 
 func (c *Client) readSpec(buf []byte) error {
+	buf, err := c.migrateSpec(buf)
+	if err != nil {
+		return err
+	}
 	if err := yaml.Unmarshal(buf, &c.buildSpec); err != nil {
 		return errors.Wrapf(err, "unable to parse build file")
 	}
+	c.lintSuppress = parseLintSuppression(buf)
 
 	return nil
 }
@@ -2,25 +2,44 @@ package client
 
 import (
 	"github.com/pkg/errors"
+	"github.com/rai-project/auth"
 	"github.com/rai-project/auth/provider"
 	"github.com/rai-project/database"
 	"github.com/rai-project/database/mongodb"
 	upper "upper.io/db.v3"
 )
 
+// FindTeamName finds the team of the user using the default profile
 func FindTeamName(uname string) (string, error) {
-
 	prof, err := provider.New()
 	if err != nil {
 		return "", err
 	}
+	return FindTeamNameWithProfile(prof, uname)
+}
+
+// findTeamName finds the team of the user using the profile selected by the client
+func (c *Client) findTeamName(uname string) (string, error) {
+	prof := c.profile
+	if prof == nil {
+		var err error
+		prof, err = c.newProfile()
+		if err != nil {
+			return "", err
+		}
+	}
+	return FindTeamNameWithProfile(prof, uname)
+}
+
+// FindTeamNameWithProfile finds the team of the user after verifying the profile
+func FindTeamNameWithProfile(prof auth.Profile, uname string) (string, error) {
 
 	ok, err := prof.Verify()
 	if err != nil {
 		return "", err
 	}
 	if !ok {
-		return "", errors.Errorf("cannot authenticate using the credentials in %v", prof.Options().ProfilePath)
+		return "", errors.Errorf("cannot authenticate using the credentials in %v", describeProfile(prof))
 	}
 
 	db, err := mongodb.NewDatabase("rai")
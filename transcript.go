@@ -0,0 +1,196 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acarl005/stripansi"
+	"github.com/fatih/color"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/rai-project/config"
+	"github.com/rai-project/model"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// TranscriptExtension is the extension of the transcript with the ANSI sequences stripped
+	TranscriptExtension = ".log"
+	// RawTranscriptExtension is the extension of the transcript with the ANSI sequences kept
+	RawTranscriptExtension = ".raw.log"
+	// transcriptTimeFormat is the format of the server timestamps in the transcript
+	transcriptTimeFormat = time.RFC3339Nano
+)
+
+// transcript writes every line of the job output to disk. Each line
+// of the transcript has the form
+//
+//	<server timestamp> <stream> | <line>
+//
+// and the transcript starts with a header of lines prefixed by '#'
+// describing the job and the resolved build specification.
+type transcript struct {
+	sync.Mutex
+	path     string
+	header   func() string
+	raw      *os.File
+	stripped *os.File
+	rawW     *bufio.Writer
+	strW     *bufio.Writer
+	err      error
+}
+
+func newTranscript(path string, header func() string) *transcript {
+	return &transcript{
+		path:   path,
+		header: header,
+	}
+}
+
+// open creates the transcript files and writes the header. It is
+// called lazily on the first line so that the header contains the
+// upload key and queue of the job.
+func (t *transcript) open() error {
+	if t.raw != nil || t.err != nil {
+		return t.err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		t.err = errors.Wrapf(err, "unable to create the transcript directory")
+		return t.err
+	}
+	raw, err := os.Create(t.path + RawTranscriptExtension)
+	if err != nil {
+		t.err = errors.Wrapf(err, "unable to create the transcript")
+		return t.err
+	}
+	stripped, err := os.Create(t.path + TranscriptExtension)
+	if err != nil {
+		raw.Close()
+		t.err = errors.Wrapf(err, "unable to create the transcript")
+		return t.err
+	}
+	t.raw, t.stripped = raw, stripped
+	t.rawW, t.strW = bufio.NewWriter(raw), bufio.NewWriter(stripped)
+
+	header := t.header()
+	t.rawW.WriteString(header)
+	t.strW.WriteString(header)
+	return nil
+}
+
+func transcriptStream(resp model.JobResponse) string {
+	if resp.Kind == model.StderrResponse {
+		return "stderr"
+	}
+	return "stdout"
+}
+
+// write appends the response to the transcript
+func (t *transcript) write(resp model.JobResponse) {
+	t.Lock()
+	defer t.Unlock()
+	if err := t.open(); err != nil {
+		return
+	}
+	at := resp.CreatedAt.Format(transcriptTimeFormat)
+	stream := transcriptStream(resp)
+	body := Secrets.Redact(string(resp.Body))
+	// the raw transcript keeps the carriage returns so that the message
+	// can be rebuilt byte for byte by splitting on the line feeds only
+	for _, line := range strings.Split(body, "\n") {
+		fmt.Fprintf(t.rawW, "%s %s | %s\n", at, stream, line)
+		fmt.Fprintf(t.strW, "%s %s | %s\n", at, stream, stripansi.Strip(strings.TrimRight(line, "\r")))
+	}
+}
+
+// Close flushes and closes the transcript files
+func (t *transcript) Close() error {
+	t.Lock()
+	defer t.Unlock()
+	if err := t.open(); err != nil {
+		return err
+	}
+	for _, w := range []*bufio.Writer{t.rawW, t.strW} {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if err := t.raw.Close(); err != nil {
+		return err
+	}
+	return t.stripped.Close()
+}
+
+// defaultTranscriptDirectory is where the transcripts are written unless
+// an output directory is set. It is outside of the project directory so
+// that the transcripts are not uploaded with the next submission.
+func defaultTranscriptDirectory() string {
+	home, err := homedir.Dir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	return filepath.Join(home, "."+config.App.Name, "transcripts")
+}
+
+// transcriptPath returns the path of the transcript without extension
+func (c *Client) transcriptPath() string {
+	if c.options.transcriptPath != "" {
+		return c.options.transcriptPath
+	}
+	if dir := c.options.outputDirectory; dir != "" {
+		return filepath.Join(dir, config.App.Name+"_transcripts", c.ID.Hex())
+	}
+	return filepath.Join(defaultTranscriptDirectory(), c.ID.Hex())
+}
+
+// transcriptHeader describes the job at the top of the transcript
+func (c *Client) transcriptHeader() string {
+	lines := []string{
+		"job_id: " + c.ID.Hex(),
+		"upload_key: " + c.uploadKey,
+		"queue: " + c.JobQueueName(),
+		"client_version: " + config.App.Version,
+		"created_at: " + time.Now().Format(transcriptTimeFormat),
+		"build_specification:",
+	}
+	if spec, err := yaml.Marshal(c.buildSpec); err == nil {
+		for _, line := range strings.Split(strings.TrimRight(string(spec), "\n"), "\n") {
+			lines = append(lines, "  "+line)
+		}
+	}
+	return Secrets.Redact("# " + strings.Join(lines, "\n# ") + "\n")
+}
+
+// startTranscript starts recording the job output if requested
+func (c *Client) startTranscript() {
+	if !c.options.recordTranscript {
+		return
+	}
+	c.transcript = newTranscript(c.transcriptPath(), c.transcriptHeader)
+}
+
+// closeTranscript closes the transcript and tells the user where it is
+func (c *Client) closeTranscript() {
+	if c.transcript == nil {
+		return
+	}
+	if err := c.transcript.Close(); err != nil {
+		log.WithError(err).Error("failed to write the job transcript")
+		return
+	}
+	fprintln(c.options.stdout, color.GreenString("✱ The job transcript has been written to "+c.transcript.path+TranscriptExtension))
+}
+
+// TranscriptPath returns the path of the job transcript, or an empty
+// string if the transcript is not recorded
+func (c *Client) TranscriptPath() string {
+	if c.transcript == nil {
+		return ""
+	}
+	return c.transcript.path + TranscriptExtension
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rai-project/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transcript")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	buf := new(bytes.Buffer)
+	c := &Client{
+		connection: newConnection(),
+		options:    Options{directory: dir},
+		stdout:     nopWriterCloser{buf},
+		stderr:     nopWriterCloser{buf},
+	}
+	newJob(c)
+	c.uploadKey = "userdata/build-file.tar.gz"
+
+	path := filepath.Join(dir, "job")
+	tr := newTranscript(path, c.transcriptHeader)
+	at := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	messages := []model.JobResponse{
+		{Kind: model.StdoutResponse, CreatedAt: at, Body: []byte("Loading model... done\r\nNew Inference")},
+		{Kind: model.StderrResponse, CreatedAt: at.Add(time.Second), Body: []byte("\x1b[31mOp Time: 0.1\x1b[0m\n")},
+	}
+	for _, resp := range messages {
+		tr.write(resp)
+	}
+	require.NoError(t, tr.Close())
+
+	stripped, err := ioutil.ReadFile(path + TranscriptExtension)
+	require.NoError(t, err)
+	lines := strings.Split(string(stripped), "\n")
+	assert.Equal(t, "# job_id: "+c.ID.Hex(), lines[0])
+	assert.Equal(t, "# upload_key: userdata/build-file.tar.gz", lines[1])
+	assert.Contains(t, string(stripped), "# build_specification:\n")
+	assert.Contains(t, string(stripped), "\n2018-06-01T10:00:00Z stdout | Loading model... done\n"+
+		"2018-06-01T10:00:00Z stdout | New Inference\n"+
+		"2018-06-01T10:00:01Z stderr | Op Time: 0.1\n"+
+		"2018-06-01T10:00:01Z stderr | \n")
+
+	// the raw transcript is replayed byte for byte
+	raw, err := os.Open(path + RawTranscriptExtension)
+	require.NoError(t, err)
+	defer raw.Close()
+	r := &transcriptReplayer{}
+	require.NoError(t, r.read(raw))
+	assert.Equal(t, c.ID.Hex(), r.jobID)
+	require.Len(t, r.messages, len(messages))
+	for ii, msg := range r.messages {
+		resp := msg.response()
+		assert.Equal(t, messages[ii].Kind, resp.Kind)
+		assert.Equal(t, string(messages[ii].Body), string(resp.Body))
+		assert.True(t, messages[ii].CreatedAt.Equal(resp.CreatedAt))
+	}
+}
+
+func TestTranscriptPathOutsideProject(t *testing.T) {
+	c := &Client{connection: newConnection(), options: Options{directory: "/projects/a"}}
+	newJob(c)
+	assert.False(t, strings.HasPrefix(c.transcriptPath(), "/projects/a"))
+
+	c.options.outputDirectory = "/tmp/out"
+	assert.True(t, strings.HasPrefix(c.transcriptPath(), "/tmp/out/"))
+}
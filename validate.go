@@ -10,12 +10,10 @@ import (
 //  - authentication, roles
 //  - run custom prevalidation steps
 //  - existance and validity of the build spec file
+//  - non-fatal lint warnings for the build spec file
 func (c *Client) Validate() error {
-
-	options := c.options
-
 	// Authenticate user using their profile
-	if err := c.authenticate(options.profilePath); err != nil {
+	if err := c.authenticate(); err != nil {
 		return err
 	}
 
@@ -34,6 +32,7 @@ func (c *Client) Validate() error {
 		if err != nil {
 			return err
 		}
+		c.specFilePath = specFilePath
 
 		// Read the build spec file into a buffer
 		buf, err := ioutil.ReadFile(specFilePath)
@@ -45,6 +44,30 @@ func (c *Client) Validate() error {
 		if err := c.readSpec(buf); err != nil {
 			return err
 		}
+
+		// Write the upgraded build file back if it was
+		// migrated from an older rai.version
+		if err := c.writeMigratedSpec(specFilePath); err != nil {
+			return err
+		}
+	}
+
+	// The build file may contain the docker push credentials
+	c.registerSecrets()
+
+	// Check the Dockerfile used to build the image locally
+	// so that errors do not only surface on the server
+	if err := c.validateBuildImage(); err != nil {
+		return err
 	}
+
+	// Lint warnings are surfaced to the user but do not
+	// fail the validation
+	c.lintWarnings = c.lint()
+
+	// Infer the job queue from the build file. This is only used
+	// if the queue is not specified as an option
+	c.selectBuildQueue()
+
 	return nil
 }
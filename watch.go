@@ -0,0 +1,271 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/rai-project/config"
+)
+
+// WatchPolicy decides what happens to the job in flight when the
+// project changes in watch mode
+type WatchPolicy string
+
+const (
+	// WatchCancelPrevious cancels the job in flight on the server
+	WatchCancelPrevious WatchPolicy = "cancel"
+	// WatchSupersedePrevious leaves the job in flight running on the
+	// server but stops following its output
+	WatchSupersedePrevious WatchPolicy = "supersede"
+)
+
+// defaultWatchIgnore are the paths that never trigger a resubmission
+var defaultWatchIgnore = []string{
+	".git",
+	".hg",
+	".svn",
+	".DS_Store",
+	"*.swp",
+	"*.swx",
+	"*~",
+	"#*#",
+}
+
+// watchIgnoreFile is the name of the file listing the paths, relative to
+// the project directory, that do not trigger a resubmission. It uses a
+// subset of the .gitignore syntax: one glob per line, with # comments.
+func watchIgnoreFile() string {
+	return "." + config.App.Name + "ignore"
+}
+
+// watchIgnore matches the paths ignored by the watch mode
+type watchIgnore struct {
+	patterns []string
+}
+
+func readWatchIgnore(dir string, extra ...string) watchIgnore {
+	patterns := append([]string{}, defaultWatchIgnore...)
+	patterns = append(patterns, config.App.Name+"_transcripts", watchIgnoreFile())
+	patterns = append(patterns, extra...)
+	if f, err := os.Open(filepath.Join(dir, watchIgnoreFile())); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			patterns = append(patterns, line)
+		}
+	}
+	ignore := watchIgnore{}
+	for _, p := range patterns {
+		p = strings.TrimPrefix(strings.TrimSuffix(filepath.ToSlash(p), "/"), "/")
+		if p != "" {
+			ignore.patterns = append(ignore.patterns, p)
+		}
+	}
+	return ignore
+}
+
+// Match returns true if the path, relative to the project directory,
+// or any of its parent directories is ignored. Patterns without a
+// slash match the name of any file or directory, others match the
+// path from the project directory.
+func (w watchIgnore) Match(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	parts := strings.Split(rel, "/")
+	for ii := range parts {
+		name := parts[ii]
+		prefix := strings.Join(parts[:ii+1], "/")
+		for _, p := range w.patterns {
+			if strings.Contains(p, "/") {
+				if ok, _ := filepath.Match(p, prefix); ok {
+					return true
+				}
+				continue
+			}
+			if ok, _ := filepath.Match(p, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Client) watchPolicy() WatchPolicy {
+	if c.options.watchPolicy != "" {
+		return c.options.watchPolicy
+	}
+	if Config.WatchPolicy != "" {
+		return WatchPolicy(Config.WatchPolicy)
+	}
+	return WatchCancelPrevious
+}
+
+// addWatches watches dir and its sub directories that are not ignored
+func addWatches(watcher *fsnotify.Watcher, root, dir string, ignore watchIgnore) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." && ignore.Match(rel) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// printResultDiff prints what changed between two consecutive runs
+func (c *Client) printResultDiff(previous, current JobResult) {
+	changes := []string{}
+	if previous.Status != current.Status {
+		changes = append(changes, "status: "+string(previous.Status)+" → "+string(current.Status))
+	}
+	for _, ch := range DiffMetrics(previous.Metrics, current.Metrics) {
+		changes = append(changes, ch.String())
+	}
+	if len(changes) == 0 {
+		fprintln(c.options.stdout, color.CyanString("✱ No change since job "+previous.JobID+"."))
+		return
+	}
+	fprintln(c.options.stdout, color.CyanString("✱ Changes since job "+previous.JobID+":"))
+	for _, ch := range changes {
+		fprintln(c.options.stdout, "  "+ch)
+	}
+}
+
+// Watch submits the project directory and resubmits it every time a
+// file changes, until the context is done. The changes are debounced
+// and the paths matched by the .<app>ignore file are ignored. The job
+// in flight when the project changes is cancelled or superseded
+// depending on the watch policy. The metrics of each completed job are
+// compared to the ones of the previous completed job.
+func (c *Client) Watch(ctx context.Context) error {
+	dir := c.options.directory
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "unable to watch the project directory")
+	}
+	defer watcher.Close()
+
+	extra := append([]string{}, Config.WatchIgnore...)
+	if out := c.options.outputDirectory; out != "" {
+		if rel, err := filepath.Rel(dir, out); err == nil && !strings.HasPrefix(rel, "..") {
+			extra = append(extra, filepath.ToSlash(rel))
+		}
+	}
+	ignore := readWatchIgnore(dir, extra...)
+	if err := addWatches(watcher, dir, dir, ignore); err != nil {
+		return errors.Wrapf(err, "unable to watch %v", dir)
+	}
+
+	type completion struct {
+		job *Job
+		err error
+	}
+	completed := make(chan completion)
+
+	var current *Job
+	var previous *JobResult
+
+	submit := func() {
+		job, err := c.Submit(ctx)
+		if err != nil {
+			log.WithError(err).Error("failed to submit the project")
+			return
+		}
+		current = job
+		go func() {
+			err := job.Wait()
+			select {
+			case completed <- completion{job: job, err: err}:
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	// stop following the job in flight, cancelling it if requested
+	replace := func() {
+		if current == nil {
+			return
+		}
+		job := current
+		current = nil
+		if c.watchPolicy() == WatchCancelPrevious {
+			cancelCtx, cancel := context.WithTimeout(ctx, Config.CancelTimeout)
+			_, err := job.Cancel(cancelCtx)
+			cancel()
+			if err == nil {
+				return
+			}
+			log.WithError(err).Error("failed to cancel the previous job")
+		}
+		fprintln(c.options.stdout, color.YellowString("✱ Job "+job.ID.Hex()+" has been superseded."))
+		job.abort()
+	}
+
+	fprintln(c.options.stdout, color.GreenString("✱ Watching "+dir+" for changes."))
+	submit()
+
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, ev.Name)
+			if err != nil || ignore.Match(rel) {
+				continue
+			}
+			if ev.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					addWatches(watcher, dir, ev.Name, ignore)
+				}
+			}
+			log.WithField("path", rel).WithField("op", ev.Op.String()).Debug("project changed")
+			debounce.Reset(Config.WatchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.WithError(err).Error("error while watching the project directory")
+		case <-debounce.C:
+			fprintln(c.options.stdout, color.YellowString("✱ The project has changed. Resubmitting."))
+			replace()
+			submit()
+		case done := <-completed:
+			if done.job != current {
+				// superseded or cancelled
+				continue
+			}
+			current = nil
+			if done.err != nil {
+				log.WithError(done.err).Error("the job did not complete")
+				continue
+			}
+			result := done.job.Result()
+			if previous != nil {
+				c.printResultDiff(*previous, result)
+			}
+			previous = &result
+			fprintln(c.options.stdout, color.GreenString("✱ Watching "+dir+" for changes."))
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchIgnore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ignoreFile := "# generated files\nbuild/\n*.o\n\ndata/*.csv\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, watchIgnoreFile()), []byte(ignoreFile), 0644))
+
+	ignore := readWatchIgnore(dir, "outputs")
+
+	assert.True(t, ignore.Match(".git/HEAD"))
+	assert.True(t, ignore.Match("src/.main.cu.swp"))
+	assert.True(t, ignore.Match("build"))
+	assert.True(t, ignore.Match("build/ecelayer.o"))
+	assert.True(t, ignore.Match("src/kernel.o"))
+	assert.True(t, ignore.Match("data/test.csv"))
+	assert.True(t, ignore.Match("outputs/run.log"))
+	assert.True(t, ignore.Match(watchIgnoreFile()))
+
+	assert.False(t, ignore.Match("src/new-forward.cuh"))
+	assert.False(t, ignore.Match("src/data/test.csv"))
+	assert.False(t, ignore.Match("CMakeLists.txt"))
+}